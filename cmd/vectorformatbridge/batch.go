@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
+)
+
+// batchJob is one file of a batch, named relative to the input directory
+type batchJob struct {
+	rel string
+	err error
+}
+
+// runBatch converts every matching file below the input directory into
+// the same layout below the output directory, continuing past failures
+func runBatch(c *command, args []string) error {
+	fs := c.flagSet()
+	t := addTreeFlags(fs)
+	workers := fs.Int("workers", runtime.NumCPU(), "number of files converted concurrently")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageErrorf("unexpected arguments %s", strings.Join(fs.Args(), " "))
+	}
+	if *workers < 1 {
+		return usageErrorf("-workers must be at least 1")
+	}
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	files, err := t.files()
+	if err != nil {
+		return ioError(err)
+	}
+	jobs := make([]*batchJob, len(files))
+	for i, f := range files {
+		jobs[i] = &batchJob{rel: f.rel}
+	}
+
+	var mu sync.Mutex
+	warn := func(rel string) func(*egf.DecodeError) {
+		return func(w *egf.DecodeError) {
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", rel, w)
+		}
+	}
+
+	queue := make(chan *batchJob)
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				job.err = t.convert(job.rel, warn(job.rel))
+			}
+		}()
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	return batchSummary(jobs)
+}
+
+// batchSummary prints the failures and totals. The batch fails with the
+// status shared by all failures, or the generic status if they differ.
+func batchSummary(jobs []*batchJob) error {
+	failed, code := 0, 0
+	for _, job := range jobs {
+		if job.err == nil {
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL  %s: %v\n", job.rel, job.err)
+		if c := exitCode(job.err); code == 0 {
+			code = c
+		} else if c != code {
+			code = exitFailure
+		}
+	}
+
+	fmt.Printf("Converted %d of %d files, %d failed\n", len(jobs)-failed, len(jobs), failed)
+	if failed == 0 {
+		return nil
+	}
+	return &exitError{code: code, err: fmt.Errorf("%d of %d files failed", failed, len(jobs))}
+}
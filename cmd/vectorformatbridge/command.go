@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Exit statuses
+const (
+	exitOK      = 0
+	exitFailure = 1 // any other failure
+	exitUsage   = 2 // bad command line
+	exitParse   = 3 // input could not be parsed or decoded
+	exitIO      = 4 // a file could not be read or written
+)
+
+// command is a subcommand of the CLI
+type command struct {
+	name string
+	// args is the synopsis shown after the command name
+	args    string
+	summary string
+	// help is shown below the synopsis by "help <command>"
+	help string
+	run  func(c *command, args []string) error
+}
+
+// exitError carries the exit status for an error
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &exitError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+func parseError(err error) error {
+	return &exitError{code: exitParse, err: err}
+}
+
+func ioError(err error) error {
+	return &exitError{code: exitIO, err: err}
+}
+
+// exitCode returns the exit status for err. Errors not marked by the
+// commands fall back to their type: file errors are I/O errors.
+func exitCode(err error) int {
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return exitIO
+	}
+	return exitFailure
+}
+
+// flagSet returns a flag set whose errors are reported by execute rather
+// than printed by the flag package
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.Usage = func() {}
+	return fs
+}
+
+// parse parses the command's flags, printing help for -h
+func (c *command) parse(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		c.printHelp(fs)
+		return err
+	}
+	if err != nil {
+		return usageErrorf("%v", err)
+	}
+	return nil
+}
+
+// printHelp writes the synopsis, description and flags to stdout
+func (c *command) printHelp(fs *flag.FlagSet) {
+	fmt.Printf("Usage: vectorformatbridge %s %s\n", c.name, c.args)
+	fmt.Println()
+	fmt.Println(c.summary + ".")
+	if c.help != "" {
+		fmt.Println()
+		fmt.Println(strings.TrimSpace(c.help))
+	}
+	if fs == nil {
+		return
+	}
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.SetOutput(os.Stdout)
+		fs.PrintDefaults()
+	}
+}
+
+// execute runs the command and returns the process exit status, reporting
+// any error on stderr
+func (c *command) execute(args []string) int {
+	err := c.run(c, args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	fmt.Fprintf(os.Stderr, "vectorformatbridge %s: %v\n", c.name, err)
+	code := exitCode(err)
+	if code == exitUsage {
+		fmt.Fprintf(os.Stderr, "Run 'vectorformatbridge help %s' for usage.\n", c.name)
+	}
+	return code
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/converter"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
+)
+
+// egfbFlags are the EGFB options shared by the commands that convert
+type egfbFlags struct {
+	checksum  *bool
+	chunk     *int
+	precision *int
+	compress  *int
+	frame     *bool
+	lenient   *bool
+}
+
+func addEGFBFlags(fs *flag.FlagSet) *egfbFlags {
+	return &egfbFlags{
+		checksum:  fs.Bool("checksum", false, "append a CRC32 checksum"),
+		chunk:     fs.Int("chunk", 0, "also checksum every `n` bytes of records (implies -checksum)"),
+		precision: fs.Int("precision", -1, "round numbers to `n` decimal places 0-5 (lossy; -1 keeps them exact)"),
+		compress:  fs.Int("compress", 0, "deflate the records at `level` 1-9 (0 disables)"),
+		frame:     fs.Bool("frame", false, "length-prefix records so -lenient can skip a damaged one"),
+		lenient:   fs.Bool("lenient", false, "salvage damaged EGFB input, reporting the damage as warnings"),
+	}
+}
+
+func (f *egfbFlags) options() egf.EncodeOptions {
+	return egf.EncodeOptions{
+		Quantize:  *f.precision >= 0,
+		Decimals:  *f.precision,
+		Checksum:  *f.checksum || *f.chunk > 0,
+		ChunkSize: *f.chunk,
+
+		Compress:         *f.compress != 0,
+		CompressionLevel: *f.compress,
+		Frame:            *f.frame,
+	}
+}
+
+// validate reports option values the encoder would reject as usage errors
+func (f *egfbFlags) validate() error {
+	if *f.precision < -1 || *f.precision > 5 {
+		return usageErrorf("-precision must be between 0 and 5, or -1 for exact numbers")
+	}
+	if *f.chunk < 0 {
+		return usageErrorf("-chunk must not be negative")
+	}
+	if *f.compress < 0 || *f.compress > 9 {
+		return usageErrorf("-compress must be between 1 and 9, or 0 to disable")
+	}
+	return nil
+}
+
+// codecs returns the decoder for from and the encoder for to, with the
+// EGFB options applied to whichever side is EGFB. Lenient-mode warnings
+// are passed to warn.
+func (f *egfbFlags) codecs(from, to string, warn func(*egf.DecodeError)) (converter.Decoder, converter.Encoder, error) {
+	dec, err := converter.DecoderFor(from)
+	if err != nil {
+		return nil, nil, usageErrorf("%v", err)
+	}
+	enc, err := converter.EncoderFor(to)
+	if err != nil {
+		return nil, nil, usageErrorf("%v", err)
+	}
+	if _, ok := dec.(converter.EGFBDecoder); ok {
+		dec = converter.EGFBDecoder{Options: egf.DecodeOptions{Lenient: *f.lenient}, Warn: warn}
+	}
+	if _, ok := enc.(converter.EGFBEncoder); ok {
+		enc = converter.EGFBEncoder{Options: f.options()}
+	}
+	return dec, enc, nil
+}
+
+// transcode converts data in memory, marking decode failures as parse
+// errors
+func transcode(data []byte, dec converter.Decoder, enc converter.Encoder) ([]byte, error) {
+	doc, err := dec.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, parseError(err)
+	}
+	var out bytes.Buffer
+	if err := enc.Encode(&out, doc); err != nil {
+		return nil, fmt.Errorf("encoding: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// newAlias returns one of the original pairwise commands, which are now
+// shorthands for convert with fixed formats
+func newAlias(name, from, to string) *command {
+	return &command{
+		name:    name,
+		args:    "[options] <input> <output>",
+		summary: fmt.Sprintf("Convert %s to %s, same as convert -from %s -to %s", strings.ToUpper(from), strings.ToUpper(to), from, to),
+		run: func(c *command, args []string) error {
+			return runConvert(c, append([]string{"-from", from, "-to", to}, args...))
+		},
+	}
+}
+
+// runConvert converts one file. Either path may be "-" for stdin or
+// stdout; messages then go to stderr so they stay out of the pipeline.
+func runConvert(c *command, args []string) error {
+	fs := c.flagSet()
+	from := fs.String("from", "", "input `format`, detected from the content if omitted")
+	to := fs.String("to", "", "output `format`, taken from the output file extension if omitted")
+	encoding := addEGFBFlags(fs)
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageErrorf("expected an input and an output, got %d arguments", fs.NArg())
+	}
+	if err := encoding.validate(); err != nil {
+		return err
+	}
+	input, output := fs.Arg(0), fs.Arg(1)
+	msg := os.Stdout
+	if output == "-" {
+		msg = os.Stderr
+	}
+
+	var data []byte
+	var err error
+	if input == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(input)
+	}
+	if err != nil {
+		return ioError(err)
+	}
+	if *from == "" {
+		name, ok := converter.DetectFormat(data)
+		if !ok && input != "-" {
+			// Fall back to the extension, so a damaged file is reported
+			// by its decoder rather than as unrecognizable
+			name, ok = converter.FormatForPath(input)
+		}
+		if !ok {
+			return usageErrorf("cannot detect the input format, use -from")
+		}
+		*from = name
+	}
+	if *to == "" {
+		name, ok := converter.FormatForPath(output)
+		if !ok || output == "-" {
+			return usageErrorf("cannot tell the output format from the file name, use -to")
+		}
+		*to = name
+	}
+
+	dec, enc, err := encoding.codecs(*from, *to, func(w *egf.DecodeError) {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", w)
+	})
+	if err != nil {
+		return err
+	}
+	out, err := transcode(data, dec, enc)
+	if err != nil {
+		return err
+	}
+	if output == "-" {
+		_, err = os.Stdout.Write(out)
+	} else {
+		err = ioutil.WriteFile(output, out, 0644)
+	}
+	if err != nil {
+		return ioError(err)
+	}
+	fmt.Fprintf(msg, "Converted %s to %s successfully.\n", strings.ToUpper(*from), strings.ToUpper(*to))
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/converter"
+)
+
+func runDemo(c *command, args []string) error {
+	fs := c.flagSet()
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageErrorf("demo takes no arguments")
+	}
+
+	fmt.Println("Running VectorFormatBridge demo...")
+
+	// Create a sample SVG file
+	sampleSVG := `<svg xmlns="http://www.w3.org/2000/svg" width="400" height="300">
+  <rect x="50" y="50" width="100" height="75" fill="#ff0000" stroke="#000000"/>
+  <circle cx="200" cy="150" r="40" fill="#00ff00" stroke="#000000"/>
+  <line x1="300" y1="50" x2="350" y2="100" stroke="#0000ff"/>
+  <ellipse cx="150" cy="200" rx="30" ry="20" fill="#ffff00" stroke="#000000"/>
+  <polygon points="250,200 270,220 250,240 230,220" fill="#ff00ff" stroke="#000000"/>
+</svg>`
+
+	// Write sample SVG
+	err := ioutil.WriteFile("demo.svg", []byte(sampleSVG), 0644)
+	if err != nil {
+		return ioError(err)
+	}
+	fmt.Println("Created demo.svg")
+
+	// Convert SVG to EGF
+	err = demoConvert("demo.svg", "demo.egf", "svg", "egf")
+	if err != nil {
+		return fmt.Errorf("converting SVG to EGF: %w", err)
+	}
+	fmt.Println("Converted demo.svg to demo.egf")
+
+	// Convert EGF back to SVG
+	err = demoConvert("demo.egf", "demo_converted.svg", "egf", "svg")
+	if err != nil {
+		return fmt.Errorf("converting EGF to SVG: %w", err)
+	}
+	fmt.Println("Converted demo.egf to demo_converted.svg")
+
+	// Encode EGF to binary
+	err = demoConvert("demo.egf", "demo.egfb", "egf", "egfb")
+	if err != nil {
+		return fmt.Errorf("encoding EGF to EGFB: %w", err)
+	}
+	fmt.Println("Encoded demo.egf to demo.egfb")
+
+	// Decode binary back to EGF
+	err = demoConvert("demo.egfb", "demo_decoded.egf", "egfb", "egf")
+	if err != nil {
+		return fmt.Errorf("decoding EGFB to EGF: %w", err)
+	}
+	fmt.Println("Decoded demo.egfb to demo_decoded.egf")
+
+	fmt.Println()
+	fmt.Println("Demo completed! Files created:")
+	fmt.Println("- demo.svg (original)")
+	fmt.Println("- demo.egf (EGF format)")
+	fmt.Println("- demo_converted.svg (converted back from EGF)")
+	fmt.Println("- demo.egfb (binary format)")
+	fmt.Println("- demo_decoded.egf (decoded from binary)")
+	return nil
+}
+
+// demoConvert converts one demo file, marking failures with the exit status
+// convert would use
+func demoConvert(input, output, from, to string) error {
+	dec, err := converter.DecoderFor(from)
+	if err != nil {
+		return err
+	}
+	enc, err := converter.EncoderFor(to)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		return ioError(err)
+	}
+	out, err := transcode(data, dec, enc)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(output, out, 0644); err != nil {
+		return ioError(err)
+	}
+	return nil
+}
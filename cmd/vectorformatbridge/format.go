@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
+)
+
+func runFmt(c *command, args []string) error {
+	fs := c.flagSet()
+	write := fs.Bool("w", false, "write result to the source file instead of stdout")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("expected one file, got %d arguments", fs.NArg())
+	}
+	file := fs.Arg(0)
+	if file == "-" && *write {
+		return usageErrorf("-w cannot rewrite standard input")
+	}
+
+	var content string
+	var err error
+	if file == "-" {
+		var data []byte
+		data, err = ioutil.ReadAll(os.Stdin)
+		content = string(data)
+	} else {
+		content, err = egf.ReadEGF(file)
+	}
+	if err != nil {
+		return ioError(err)
+	}
+	formatted, err := egf.FormatSource(content)
+	if err != nil {
+		return parseError(fmt.Errorf("%s: %w", file, err))
+	}
+
+	if !*write {
+		fmt.Print(formatted)
+		return nil
+	}
+	if formatted == content {
+		return nil
+	}
+	err = egf.WriteEGF(file, formatted)
+	if err != nil {
+		return ioError(err)
+	}
+	fmt.Printf("Formatted %s\n", file)
+	return nil
+}
@@ -2,152 +2,145 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/prabinpanta0/VectorFormatBridge/pkg/converter"
 )
 
-func main() {
-	if len(os.Args) < 2 {
-		printUsage()
-		return
-	}
-
-	command := os.Args[1]
-
-	switch command {
-	case "svg2egf":
-		if len(os.Args) != 4 {
-			fmt.Println("Usage: vectorformatbridge svg2egf <input.svg> <output.egf>")
-			return
-		}
-		err := converter.SVGToEGF(os.Args[2], os.Args[3])
-		if err != nil {
-			fmt.Printf("Error converting SVG to EGF: %v\n", err)
-			return
-		}
-		fmt.Println("Converted SVG to EGF successfully.")
-
-	case "egf2svg":
-		if len(os.Args) != 4 {
-			fmt.Println("Usage: vectorformatbridge egf2svg <input.egf> <output.svg>")
-			return
-		}
-		err := converter.EGFToSVG(os.Args[2], os.Args[3])
-		if err != nil {
-			fmt.Printf("Error converting EGF to SVG: %v\n", err)
-			return
-		}
-		fmt.Println("Converted EGF to SVG successfully.")
-
-	case "egf2egfb":
-		if len(os.Args) != 4 {
-			fmt.Println("Usage: vectorformatbridge egf2egfb <input.egf> <output.egfb>")
-			return
-		}
-		err := converter.EGFToEGFB(os.Args[2], os.Args[3])
-		if err != nil {
-			fmt.Printf("Error encoding EGF to EGFB: %v\n", err)
-			return
-		}
-		fmt.Println("Encoded EGF to EGFB successfully.")
-
-	case "egfb2egf":
-		if len(os.Args) != 4 {
-			fmt.Println("Usage: vectorformatbridge egfb2egf <input.egfb> <output.egf>")
-			return
-		}
-		err := converter.EGFBToEGF(os.Args[2], os.Args[3])
-		if err != nil {
-			fmt.Printf("Error decoding EGFB to EGF: %v\n", err)
-			return
-		}
-		fmt.Println("Decoded EGFB to EGF successfully.")
-
-	case "demo":
-		runDemo()
-
-	default:
-		printUsage()
-	}
+// commands lists the subcommands in the order the usage shows them
+var commands = []*command{
+	{
+		name:    "convert",
+		args:    "[options] <input|-> <output|->",
+		summary: "Convert between any two formats",
+		help: `
+The input format is detected from the file content and the output format
+from the output file extension; -from and -to override them. Use - to read
+standard input or write standard output, in which case messages go to
+standard error. The EGFB options apply when EGFB is written or read.`,
+		run: runConvert,
+	},
+	{
+		name:    "batch",
+		args:    "-from X -to Y -in <dir> -out <dir> [options]",
+		summary: "Convert a directory tree concurrently",
+		help: `
+Files below -in that match the -include patterns (by default the input
+format's extensions) and no -exclude pattern are converted into the same
+layout below -out, with the output format's extension. Patterns match the
+file name or the slash-separated path relative to -in. A failed file does
+not stop the batch; failures are listed in the summary at the end.`,
+		run: runBatch,
+	},
+	{
+		name:    "watch",
+		args:    "-from X -to Y -in <dir> -out <dir> [options]",
+		summary: "Convert a directory tree again whenever its files change",
+		help: `
+Takes the same options as batch. After converting the tree once, -in is
+polled every -interval; a file whose content changed is converted again
+after it has been left alone for -debounce, and the output of a deleted
+file is removed. Runs until interrupted.`,
+		run: runWatch,
+	},
+	{
+		name:    "verify",
+		args:    "<file.egfb|dir>...",
+		summary: "Check EGFB files for corruption",
+		help: `
+Every named file and every .egfb file below a named directory is decoded in
+full, verifying checksums where present.`,
+		run: runVerify,
+	},
+	{
+		name:    "fmt",
+		args:    "[-w] <file.egf|->",
+		summary: "Print canonical EGF (-w rewrites the file)",
+		run:     runFmt,
+	},
+	{
+		name:    "demo",
+		args:    "",
+		summary: "Run demo with sample files",
+		run:     runDemo,
+	},
+	newAlias("svg2egf", "svg", "egf"),
+	newAlias("egf2svg", "egf", "svg"),
+	newAlias("egf2egfb", "egf", "egfb"),
+	newAlias("egfb2egf", "egfb", "egf"),
 }
 
-func printUsage() {
-	fmt.Println("VectorFormatBridge - Bridge between vector graphics formats")
-	fmt.Println()
-	fmt.Println("Format explanations:")
-	fmt.Println("  SVG  - Scalable Vector Graphics (XML-based)")
-	fmt.Println("  EGF  - Enhanced Graphics Format (text-based intermediate format)")
-	fmt.Println("  EGFB - EGF Binary (compressed binary version of EGF)")
-	fmt.Println()
-	fmt.Println("Usage:")
-	fmt.Println("  vectorformatbridge svg2egf <input.svg> <output.egf>   - Convert SVG to EGF")
-	fmt.Println("  vectorformatbridge egf2svg <input.egf> <output.svg>   - Convert EGF to SVG")
-	fmt.Println("  vectorformatbridge egf2egfb <input.egf> <output.egfb> - Encode EGF to binary EGFB")
-	fmt.Println("  vectorformatbridge egfb2egf <input.egfb> <output.egf> - Decode EGFB back to EGF")
-	fmt.Println("  vectorformatbridge demo                               - Run demo with sample files")
-	fmt.Println()
-	fmt.Println("Note: EGFB is a binary/compressed version of EGF for efficient storage.")
+func main() {
+	os.Exit(run(os.Args[1:]))
 }
 
-func runDemo() {
-	fmt.Println("Running VectorFormatBridge demo...")
-
-	// Create a sample SVG file
-	sampleSVG := `<svg xmlns="http://www.w3.org/2000/svg" width="400" height="300">
-  <rect x="50" y="50" width="100" height="75" fill="#ff0000" stroke="#000000"/>
-  <circle cx="200" cy="150" r="40" fill="#00ff00" stroke="#000000"/>
-  <line x1="300" y1="50" x2="350" y2="100" stroke="#0000ff"/>
-  <ellipse cx="150" cy="200" rx="30" ry="20" fill="#ffff00" stroke="#000000"/>
-  <polygon points="250,200 270,220 250,240 230,220" fill="#ff00ff" stroke="#000000"/>
-</svg>`
-
-	// Write sample SVG
-	err := ioutil.WriteFile("demo.svg", []byte(sampleSVG), 0644)
-	if err != nil {
-		fmt.Println("Error creating demo.svg:", err)
-		return
+// run dispatches to a subcommand and returns the exit status
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
 	}
-	fmt.Println("Created demo.svg")
 
-	// Convert SVG to EGF
-	err = converter.SVGToEGF("demo.svg", "demo.egf")
-	if err != nil {
-		fmt.Printf("Error converting SVG to EGF: %v\n", err)
-		return
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 && name == "help" {
+			c := lookupCommand(args[1])
+			if c == nil {
+				fmt.Fprintf(os.Stderr, "vectorformatbridge help: unknown command %q\n", args[1])
+				return exitUsage
+			}
+			return c.execute([]string{"-h"})
+		}
+		printUsage(os.Stdout)
+		return exitOK
 	}
-	fmt.Println("Converted demo.svg to demo.egf")
 
-	// Convert EGF back to SVG
-	err = converter.EGFToSVG("demo.egf", "demo_converted.svg")
-	if err != nil {
-		fmt.Printf("Error converting EGF to SVG: %v\n", err)
-		return
+	c := lookupCommand(name)
+	if c == nil {
+		fmt.Fprintf(os.Stderr, "vectorformatbridge: unknown command %q\n", name)
+		fmt.Fprintln(os.Stderr, "Run 'vectorformatbridge help' for usage.")
+		return exitUsage
 	}
-	fmt.Println("Converted demo.egf to demo_converted.svg")
+	return c.execute(args[1:])
+}
 
-	// Encode EGF to binary
-	err = converter.EGFToEGFB("demo.egf", "demo.egfb")
-	if err != nil {
-		fmt.Printf("Error encoding EGF to EGFB: %v\n", err)
-		return
+func lookupCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
 	}
-	fmt.Println("Encoded demo.egf to demo.egfb")
+	return nil
+}
 
-	// Decode binary back to EGF
-	err = converter.EGFBToEGF("demo.egfb", "demo_decoded.egf")
-	if err != nil {
-		fmt.Printf("Error decoding EGFB to EGF: %v\n", err)
-		return
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "VectorFormatBridge - Bridge between vector graphics formats")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Format explanations:")
+	fmt.Fprintln(w, "  SVG  - Scalable Vector Graphics (XML-based)")
+	fmt.Fprintln(w, "  EGF  - Enhanced Graphics Format (text-based intermediate format)")
+	fmt.Fprintln(w, "  EGFB - EGF Binary (compressed binary version of EGF)")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	width := 0
+	for _, c := range commands {
+		if n := len(c.name) + len(c.args); n > width {
+			width = n
+		}
 	}
-	fmt.Println("Decoded demo.egfb to demo_decoded.egf")
-
-	fmt.Println()
-	fmt.Println("Demo completed! Files created:")
-	fmt.Println("- demo.svg (original)")
-	fmt.Println("- demo.egf (EGF format)")
-	fmt.Println("- demo_converted.svg (converted back from EGF)")
-	fmt.Println("- demo.egfb (binary format)")
-	fmt.Println("- demo_decoded.egf (decoded from binary)")
+	for _, c := range commands {
+		synopsis := strings.TrimSpace(c.name + " " + c.args)
+		fmt.Fprintf(w, "  vectorformatbridge %-*s - %s\n", width+1, synopsis, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'vectorformatbridge help <command>' for the options of a command.")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Formats: %s\n", strings.Join(converter.Formats(), ", "))
+	fmt.Fprintln(w, "Note: EGFB is a binary/compressed version of EGF for efficient storage.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit status: 0 success, 1 other failure, 2 usage error,")
+	fmt.Fprintln(w, "3 input could not be parsed or decoded, 4 file could not be read or written.")
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/converter"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
+)
+
+// patterns is a repeatable flag collecting glob patterns
+type patterns []string
+
+func (p *patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patterns) Set(s string) error {
+	if _, err := path.Match(s, ""); err != nil {
+		return fmt.Errorf("bad pattern %q: %v", s, err)
+	}
+	*p = append(*p, s)
+	return nil
+}
+
+// match reports whether any pattern matches the base name or the
+// slash-separated path relative to the input directory
+func (p patterns) match(rel string) bool {
+	for _, pattern := range p {
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tree holds the flags of the commands that convert a directory tree into
+// a mirrored layout: batch and watch
+type tree struct {
+	from, to, in, out *string
+	include, exclude  patterns
+	encoding          *egfbFlags
+	// target is the output format, set by check
+	target *converter.Format
+}
+
+func addTreeFlags(fs *flag.FlagSet) *tree {
+	t := &tree{
+		from: fs.String("from", "", "input `format` (required)"),
+		to:   fs.String("to", "", "output `format` (required)"),
+		in:   fs.String("in", "", "input `directory` (required)"),
+		out:  fs.String("out", "", "output `directory` (required)"),
+	}
+	fs.Var(&t.include, "include", "convert only files matching `glob`; repeatable (default: the input format's extensions)")
+	fs.Var(&t.exclude, "exclude", "skip files matching `glob`; repeatable")
+	t.encoding = addEGFBFlags(fs)
+	return t
+}
+
+// check validates the flags once parsed and fills in the defaults
+func (t *tree) check() error {
+	if *t.from == "" || *t.to == "" || *t.in == "" || *t.out == "" {
+		return usageErrorf("-from, -to, -in and -out are required")
+	}
+	if err := t.encoding.validate(); err != nil {
+		return err
+	}
+	// Resolve the formats up front so a typo fails before any work
+	if _, _, err := t.encoding.codecs(*t.from, *t.to, nil); err != nil {
+		return err
+	}
+	source, _ := converter.Lookup(*t.from)
+	t.target, _ = converter.Lookup(*t.to)
+	if len(t.target.Extensions) == 0 {
+		return usageErrorf("format %s has no file extension", t.target.Name)
+	}
+	if len(t.include) == 0 {
+		for _, ext := range source.Extensions {
+			t.include = append(t.include, "*"+ext)
+		}
+	}
+	return nil
+}
+
+// treeFile is a source file, named relative to the input directory
+type treeFile struct {
+	rel  string
+	info os.FileInfo
+}
+
+// files walks the input tree for files matching include but not exclude,
+// skipping the output directory when it lies inside the input
+func (t *tree) files() ([]treeFile, error) {
+	outAbs, err := filepath.Abs(*t.out)
+	if err != nil {
+		return nil, err
+	}
+	var files []treeFile
+	err = filepath.Walk(*t.in, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if abs, err := filepath.Abs(file); err == nil && abs == outAbs && file != *t.in {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(*t.in, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if t.include.match(rel) && !t.exclude.match(rel) {
+			files = append(files, treeFile{rel: rel, info: info})
+		}
+		return nil
+	})
+	return files, err
+}
+
+// output returns the output path for a source file
+func (t *tree) output(rel string) string {
+	rel = strings.TrimSuffix(rel, path.Ext(rel)) + t.target.Extensions[0]
+	return filepath.Join(*t.out, filepath.FromSlash(rel))
+}
+
+// convert converts one source file, creating the output directory.
+// Lenient-mode warnings are passed to warn.
+func (t *tree) convert(rel string, warn func(*egf.DecodeError)) error {
+	dec, enc, err := t.encoding.codecs(*t.from, *t.to, warn)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(*t.in, filepath.FromSlash(rel)))
+	if err != nil {
+		return ioError(err)
+	}
+	result, err := transcode(data, dec, enc)
+	if err != nil {
+		return err
+	}
+	outFile := t.output(rel)
+	if err := os.MkdirAll(filepath.Dir(outFile), 0755); err != nil {
+		return ioError(err)
+	}
+	if err := ioutil.WriteFile(outFile, result, 0644); err != nil {
+		return ioError(err)
+	}
+	return nil
+}
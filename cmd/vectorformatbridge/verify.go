@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
+)
+
+// runVerify decodes every EGFB file named on the command line, searching
+// directories for .egfb files. Unreadable files make it exit with the I/O
+// status, corrupt ones with the parse status.
+func runVerify(c *command, args []string) error {
+	fs := c.flagSet()
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageErrorf("expected at least one file or directory")
+	}
+
+	var files []string
+	for _, arg := range fs.Args() {
+		err := filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// Named files are checked whatever their extension
+			if !info.IsDir() && (path == arg || strings.EqualFold(filepath.Ext(path), ".egfb")) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return ioError(err)
+		}
+	}
+
+	corrupt, unreadable := 0, 0
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			unreadable++
+			fmt.Printf("FAIL  %s: %v\n", file, err)
+			continue
+		}
+		h, err := egf.Verify(data)
+		if err != nil {
+			corrupt++
+			fmt.Printf("FAIL  %s: %v\n", file, err)
+			continue
+		}
+		checked := "no checksum"
+		if h.Flags&egf.FlagChecksum != 0 {
+			checked = "checksum ok"
+		}
+		fmt.Printf("OK    %s (version %d, %s)\n", file, h.Version, checked)
+	}
+
+	failed := corrupt + unreadable
+	fmt.Printf("Verified %d files, %d failed\n", len(files), failed)
+	switch {
+	case unreadable > 0:
+		return ioError(fmt.Errorf("%d of %d files could not be read", unreadable, len(files)))
+	case corrupt > 0:
+		return parseError(fmt.Errorf("%d of %d files are corrupt", corrupt, len(files)))
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
+)
+
+// watched is the last seen state of a source file
+type watched struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+	// changed is when the content was last seen to change; the file is
+	// converted once it has been stable for the debounce period
+	changed time.Time
+	pending bool
+}
+
+// watcher polls a tree, converting sources whose content changed and
+// removing the outputs of deleted sources
+type watcher struct {
+	tree     *tree
+	debounce time.Duration
+	sources  map[string]*watched
+}
+
+// runWatch converts the input tree like batch, then keeps polling it and
+// converts files again as they change until interrupted
+func runWatch(c *command, args []string) error {
+	fs := c.flagSet()
+	t := addTreeFlags(fs)
+	interval := fs.Duration("interval", 500*time.Millisecond, "how often to poll the input directory")
+	debounce := fs.Duration("debounce", 300*time.Millisecond, "wait until a file has been unchanged this long before converting it")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageErrorf("unexpected arguments %s", strings.Join(fs.Args(), " "))
+	}
+	if *interval <= 0 {
+		return usageErrorf("-interval must be positive")
+	}
+	if *debounce < 0 {
+		return usageErrorf("-debounce must not be negative")
+	}
+	if err := t.check(); err != nil {
+		return err
+	}
+	if info, err := os.Stat(*t.in); err != nil {
+		return ioError(err)
+	} else if !info.IsDir() {
+		return usageErrorf("%s is not a directory", *t.in)
+	}
+
+	w := &watcher{tree: t, debounce: *debounce, sources: make(map[string]*watched)}
+	fmt.Printf("Watching %s, writing %s files to %s (interrupt to stop)\n", *t.in, t.target.Name, *t.out)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	defer signal.Stop(stop)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	// The files found on the first poll count as settled, so the tree is
+	// converted straight away instead of after the debounce
+	w.poll(time.Now(), true)
+	for {
+		select {
+		case <-stop:
+			fmt.Println("Stopped watching")
+			return nil
+		case now := <-ticker.C:
+			w.poll(now, false)
+		}
+	}
+}
+
+// poll scans the tree once. A file whose modification time or size moved
+// is rehashed, and only a change of content schedules a conversion. On the
+// initial poll new files are treated as already stable.
+func (w *watcher) poll(now time.Time, initial bool) {
+	files, err := w.tree.files()
+	if err != nil {
+		// The tree may be mid-rename; try again on the next poll
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		seen[f.rel] = true
+		s := w.sources[f.rel]
+		if s != nil && f.info.ModTime().Equal(s.modTime) && f.info.Size() == s.size {
+			w.settle(f.rel, s, now)
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(*w.tree.in, filepath.FromSlash(f.rel)))
+		if err != nil {
+			// Deleted or unreadable between the walk and the read
+			continue
+		}
+		hash := sha256.Sum256(data)
+		if s == nil {
+			s = &watched{hash: hash, changed: now, pending: true}
+			if initial {
+				s.changed = now.Add(-w.debounce)
+			}
+			w.sources[f.rel] = s
+		} else if hash != s.hash {
+			s.hash, s.changed, s.pending = hash, now, true
+		}
+		s.modTime, s.size = f.info.ModTime(), f.info.Size()
+		w.settle(f.rel, s, now)
+	}
+
+	for rel := range w.sources {
+		if seen[rel] {
+			continue
+		}
+		delete(w.sources, rel)
+		out := w.tree.output(rel)
+		if err := os.Remove(out); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("FAIL  %s: %v\n", rel, err)
+		} else if err == nil {
+			fmt.Printf("Removed %s\n", out)
+		}
+	}
+}
+
+// settle converts a pending file once it has stopped changing
+func (w *watcher) settle(rel string, s *watched, now time.Time) {
+	if !s.pending || now.Sub(s.changed) < w.debounce {
+		return
+	}
+	s.pending = false
+	err := w.tree.convert(rel, func(d *egf.DecodeError) {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", rel, d)
+	})
+	if err != nil {
+		fmt.Printf("FAIL  %s: %v\n", rel, err)
+		return
+	}
+	fmt.Printf("Converted %s\n", rel)
+}
@@ -0,0 +1,156 @@
+package color
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Kind distinguishes real colors from the paint keywords that have no RGBA
+// value of their own
+type Kind uint8
+
+const (
+	// RGBA is a concrete color, possibly translucent
+	RGBA Kind = iota
+	// None paints nothing (SVG "none")
+	None
+	// Current is CSS currentColor, the value of the color property
+	Current
+)
+
+// Color is a parsed CSS color. R, G, B and A are 8-bit sRGB channels and
+// alpha, meaningful only when Kind is RGBA.
+type Color struct {
+	Kind       Kind
+	R, G, B, A uint8
+}
+
+// Black is opaque black, the initial CSS color
+var Black = Color{A: 255}
+
+// SyntaxError reports a color that could not be parsed
+type SyntaxError struct {
+	Input string
+	Msg   string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid color %q: %s", e.Input, e.Msg)
+}
+
+// Parse parses any CSS color: hex (#rgb, #rgba, #rrggbb, #rrggbbaa), named
+// colors, transparent, currentColor, none, and the rgb(), rgba(), hsl(),
+// hsla(), hwb(), lab(), lch(), oklab(), oklch() and color() functions in
+// both their comma and space separated forms. Keywords are case-insensitive.
+// The "#none" written by earlier EGF versions is read as none.
+func Parse(s string) (Color, error) {
+	in := strings.TrimSpace(s)
+	lower := strings.ToLower(in)
+	switch lower {
+	case "":
+		return Color{}, &SyntaxError{Input: s, Msg: "empty color"}
+	case "none", "#none":
+		return Color{Kind: None}, nil
+	case "currentcolor":
+		return Color{Kind: Current}, nil
+	case "transparent":
+		return Color{}, nil
+	}
+	if lower[0] == '#' {
+		return parseHex(s, lower[1:])
+	}
+	if open := strings.IndexByte(lower, '('); open != -1 {
+		if !strings.HasSuffix(lower, ")") {
+			return Color{}, &SyntaxError{Input: s, Msg: "missing closing parenthesis"}
+		}
+		return parseFunction(s, strings.TrimSpace(lower[:open]), lower[open+1:len(lower)-1])
+	}
+	if c, ok := named[lower]; ok {
+		return c, nil
+	}
+	return Color{}, &SyntaxError{Input: s, Msg: "unknown color name"}
+}
+
+func parseHex(input, digits string) (Color, error) {
+	for i := 0; i < len(digits); i++ {
+		if !isHex(digits[i]) {
+			return Color{}, &SyntaxError{Input: input, Msg: fmt.Sprintf("bad hex digit %q", digits[i])}
+		}
+	}
+	v, _ := strconv.ParseUint(digits, 16, 32)
+	switch len(digits) {
+	case 3:
+		return Color{R: expand(v >> 8), G: expand(v >> 4), B: expand(v), A: 255}, nil
+	case 4:
+		return Color{R: expand(v >> 12), G: expand(v >> 8), B: expand(v >> 4), A: expand(v)}, nil
+	case 6:
+		return Color{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+	case 8:
+		return Color{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+	}
+	return Color{}, &SyntaxError{Input: input, Msg: "hex colors need 3, 4, 6 or 8 digits"}
+}
+
+// expand turns the low hex digit of v into a byte, so f becomes ff
+func expand(v uint64) uint8 {
+	return uint8(v&0xf) * 0x11
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f'
+}
+
+// String returns the canonical EGF form of the color: none, currentColor,
+// or the shortest lowercase hex form, with alpha digits only when the color
+// is translucent
+func (c Color) String() string {
+	switch c.Kind {
+	case None:
+		return "none"
+	case Current:
+		return "currentColor"
+	}
+	short := c.R%0x11 == 0 && c.G%0x11 == 0 && c.B%0x11 == 0 && c.A%0x11 == 0
+	switch {
+	case c.A == 255 && short:
+		return fmt.Sprintf("#%x%x%x", c.R/0x11, c.G/0x11, c.B/0x11)
+	case c.A == 255:
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	case short:
+		return fmt.Sprintf("#%x%x%x%x", c.R/0x11, c.G/0x11, c.B/0x11, c.A/0x11)
+	default:
+		return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+	}
+}
+
+// SVG returns the color as an SVG 1.1 paint and its opacity. Translucent
+// colors are split into an opaque hex color and an opacity from 0 to 1,
+// since SVG 1.1 renderers do not read alpha from the color.
+func (c Color) SVG() (string, float64) {
+	if c.Kind != RGBA {
+		return c.String(), 1
+	}
+	opaque := c
+	opaque.A = 255
+	return opaque.String(), c.Alpha()
+}
+
+// Alpha returns the opacity of the color from 0 to 1, rounded to three
+// decimal places. Keywords count as opaque.
+func (c Color) Alpha() float64 {
+	if c.Kind != RGBA {
+		return 1
+	}
+	return math.Round(float64(c.A)/255*1000) / 1000
+}
+
+// Normalize parses a color and returns its canonical EGF form
+func Normalize(s string) (string, error) {
+	c, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
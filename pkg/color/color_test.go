@@ -0,0 +1,124 @@
+package color
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// hex
+		{"#F00", "#f00"},
+		{"#ff0000", "#f00"},
+		{"#f008", "#f008"},
+		{"#FF000080", "#ff000080"},
+		{"#12345678", "#12345678"},
+		// keywords and names
+		{"red", "#f00"},
+		{"RebeccaPurple", "#639"},
+		{" blue ", "#00f"},
+		{"transparent", "#0000"},
+		{"currentcolor", "currentColor"},
+		{"none", "none"},
+		{"#none", "none"},
+		// rgb
+		{"rgb(255,0,0)", "#f00"},
+		{"rgb(255 0 0)", "#f00"},
+		{"rgb(100%,0%,0%)", "#f00"},
+		{"rgba(255,0,0,0.5)", "#ff000080"},
+		{"rgb(255 0 0 / 50%)", "#ff000080"},
+		{"rgb(255 0 0 / none)", "#f00"},
+		{"rgb(300 -10 0)", "#f00"},
+		// hsl
+		{"hsl(0,100%,50%)", "#f00"},
+		{"hsl(0 100% 50% / 0.5)", "#ff000080"},
+		{"hsla(120,100%,25%,0.5)", "#00800080"},
+		{"hsl(0.5turn 100% 50%)", "#0ff"},
+		{"hsl(200grad 100% 50%)", "#0ff"},
+		{"hsl(3.14159rad 100% 50%)", "#0ff"},
+		{"hsl(none 0% 50%)", "#808080"},
+		// hwb
+		{"hwb(0 0% 0%)", "#f00"},
+		{"hwb(120 50% 50%)", "#808080"},
+		// lab and lch
+		{"lab(50 0 0)", "#777"},
+		{"lab(54.29% 80.8 69.9)", "#f00"},
+		{"lch(54.29 106.84 40.85)", "#f00"},
+		// oklab and oklch
+		{"oklab(0.628 0.2249 0.1258)", "#f00"},
+		{"oklch(0.628 0.2577 29.23)", "#f00"},
+		{"oklch(62.8% 0.2577 29.23deg)", "#f00"},
+		// color()
+		{"color(srgb 1 0 0)", "#f00"},
+		{"color(srgb 100% 0% 0% / 0.5)", "#ff000080"},
+		{"color(srgb-linear 0.5 0.5 0.5)", "#bcbcbc"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("Normalize(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in, msg string
+	}{
+		{"", "empty color"},
+		{"#ff", "hex colors need 3, 4, 6 or 8 digits"},
+		{"#12345", "hex colors need 3, 4, 6 or 8 digits"},
+		{"#ggg", "bad hex digit 'g'"},
+		{"nosuchcolor", "unknown color name"},
+		{"rgb(1 2 3", "missing closing parenthesis"},
+		{"rgb(1,2)", "rgb() expects 3 components, got 2"},
+		{"rgb(1 2 3 4)", "rgb() expects 3 components, got 4"},
+		{"rgb(1,2 / 3)", "cannot mix commas and /"},
+		{"rgb(a b c)", `bad component "a"`},
+		{"rgb(1px 2 3)", `bad unit in "1px"`},
+		{"rgb(10deg 0 0)", `"10deg" is not a hue`},
+		{"hsl(0 100% 50% / x)", `bad component "x"`},
+		{"foo(1 2 3)", "unknown color function foo()"},
+		{"color()", "color() needs a color space"},
+		{"color(display-p3 1 0 0)", `unsupported color space "display-p3"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.in)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Parse(%q) error = %v, want a SyntaxError", tt.in, err)
+			continue
+		}
+		if serr.Input != tt.in || !strings.Contains(serr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.in, err, tt.msg)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	tests := []struct {
+		in    string
+		paint string
+		alpha float64
+	}{
+		{"#f00", "#f00", 1},
+		{"#ff000080", "#f00", 0.502},
+		{"#12345678", "#123456", 0.471},
+		{"transparent", "#000", 0},
+		{"none", "none", 1},
+		{"currentColor", "currentColor", 1},
+	}
+	for _, tt := range tests {
+		c, err := Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if paint, alpha := c.SVG(); paint != tt.paint || alpha != tt.alpha {
+			t.Errorf("Parse(%q).SVG() = %s, %g, want %s, %g", tt.in, paint, alpha, tt.paint, tt.alpha)
+		}
+	}
+}
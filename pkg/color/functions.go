@@ -0,0 +1,222 @@
+package color
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// arg is one argument of a color function
+type arg struct {
+	text    string
+	value   float64
+	percent bool
+	angle   bool // has an angle unit such as deg
+	none    bool // the CSS Color 4 none keyword, a missing component
+}
+
+// hueIndex is the position of the hue component of each function that
+// has one
+var hueIndex = map[string]int{"hsl": 0, "hsla": 0, "hwb": 0, "lch": 2, "oklch": 2}
+
+// parseFunction parses a color function given its name and the text
+// between its parentheses
+func parseFunction(input, name, body string) (Color, error) {
+	fail := func(format string, a ...interface{}) (Color, error) {
+		return Color{}, &SyntaxError{Input: input, Msg: fmt.Sprintf(format, a...)}
+	}
+
+	space := ""
+	if name == "color" {
+		fields := strings.Fields(body)
+		if len(fields) == 0 {
+			return fail("color() needs a color space")
+		}
+		space = fields[0]
+		body = strings.TrimSpace(body[len(space):])
+	}
+
+	args, alpha, err := splitArgs(body)
+	if err != nil {
+		return fail("%s", err.Error())
+	}
+	if len(args) != 3 {
+		return fail("%s() expects 3 components, got %d", name, len(args))
+	}
+	for i, v := range args {
+		if h, ok := hueIndex[name]; v.angle && (!ok || h != i) {
+			return fail("%q is not a hue", v.text)
+		}
+	}
+
+	a := 1.0
+	if alpha != nil {
+		if alpha.percent {
+			a = alpha.value / 100
+		} else if !alpha.none {
+			a = alpha.value
+		}
+	}
+
+	var r, g, b float64 // sRGB, 0 to 1
+	switch name {
+	case "rgb", "rgba":
+		ch := make([]float64, 3)
+		for i, v := range args {
+			ch[i] = v.value / 255
+			if v.percent {
+				ch[i] = v.value / 100
+			}
+		}
+		r, g, b = ch[0], ch[1], ch[2]
+	case "hsl", "hsla":
+		h, err := hue(args[0])
+		if err != nil {
+			return fail("%s", err.Error())
+		}
+		r, g, b = hslToRGB(h, args[1].value/100, args[2].value/100)
+	case "hwb":
+		h, err := hue(args[0])
+		if err != nil {
+			return fail("%s", err.Error())
+		}
+		r, g, b = hwbToRGB(h, args[1].value/100, args[2].value/100)
+	case "lab":
+		r, g, b = labToRGB(args[0].value, scale(args[1], 125), scale(args[2], 125))
+	case "lch":
+		h, err := hue(args[2])
+		if err != nil {
+			return fail("%s", err.Error())
+		}
+		c := scale(args[1], 150)
+		r, g, b = labToRGB(args[0].value, c*cosDeg(h), c*sinDeg(h))
+	case "oklab":
+		r, g, b = oklabToRGB(scale(args[0], 1), scale(args[1], 0.4), scale(args[2], 0.4))
+	case "oklch":
+		h, err := hue(args[2])
+		if err != nil {
+			return fail("%s", err.Error())
+		}
+		c := scale(args[1], 0.4)
+		r, g, b = oklabToRGB(scale(args[0], 1), c*cosDeg(h), c*sinDeg(h))
+	case "color":
+		ch := make([]float64, 3)
+		for i, v := range args {
+			ch[i] = scale(v, 1)
+		}
+		switch space {
+		case "srgb":
+			r, g, b = ch[0], ch[1], ch[2]
+		case "srgb-linear":
+			r, g, b = gamma(ch[0]), gamma(ch[1]), gamma(ch[2])
+		default:
+			return fail("unsupported color space %q", space)
+		}
+	default:
+		return fail("unknown color function %s()", name)
+	}
+
+	return Color{R: channel(r), G: channel(g), B: channel(b), A: channel(a)}, nil
+}
+
+// splitArgs splits function arguments written either as "a, b, c, d" or
+// "a b c / d", returning the alpha separately
+func splitArgs(body string) ([]arg, *arg, error) {
+	var alphaText string
+	if slash := strings.IndexByte(body, '/'); slash != -1 {
+		if strings.Contains(body, ",") {
+			return nil, nil, fmt.Errorf("cannot mix commas and /")
+		}
+		body, alphaText = body[:slash], body[slash+1:]
+	}
+	var fields []string
+	if strings.Contains(body, ",") {
+		for _, f := range strings.Split(body, ",") {
+			fields = append(fields, strings.TrimSpace(f))
+		}
+		// The comma form gives alpha as a fourth argument
+		if len(fields) == 4 {
+			alphaText, fields = fields[3], fields[:3]
+		}
+	} else {
+		fields = strings.Fields(body)
+	}
+
+	args := make([]arg, len(fields))
+	for i, f := range fields {
+		a, err := parseArg(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		args[i] = a
+	}
+	if alphaText == "" {
+		return args, nil, nil
+	}
+	alpha, err := parseArg(strings.TrimSpace(alphaText))
+	if err != nil {
+		return nil, nil, err
+	}
+	return args, &alpha, nil
+}
+
+// parseArg parses a number, percentage, angle or none
+func parseArg(s string) (arg, error) {
+	if s == "" {
+		return arg{}, fmt.Errorf("empty component")
+	}
+	if s == "none" {
+		return arg{text: s, none: true}, nil
+	}
+	num := strings.TrimRight(s, "abcdefghijklmnopqrstuvwxyz%")
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return arg{}, fmt.Errorf("bad component %q", s)
+	}
+	unit := s[len(num):]
+	switch unit {
+	case "":
+		return arg{text: s, value: v}, nil
+	case "deg", "rad", "grad", "turn":
+		return arg{text: s, value: v, angle: true}, nil
+	case "%":
+		return arg{text: s, value: v, percent: true}, nil
+	}
+	return arg{}, fmt.Errorf("bad unit in %q", s)
+}
+
+// hue returns an angle argument in degrees
+func hue(a arg) (float64, error) {
+	if a.none {
+		return 0, nil
+	}
+	if a.percent {
+		return 0, fmt.Errorf("hue %q cannot be a percentage", a.text)
+	}
+	switch {
+	case strings.HasSuffix(a.text, "grad"):
+		return a.value * 0.9, nil
+	case strings.HasSuffix(a.text, "rad"):
+		return a.value * 180 / math.Pi, nil
+	case strings.HasSuffix(a.text, "turn"):
+		return a.value * 360, nil
+	}
+	return a.value, nil
+}
+
+// scale returns a component's value, mapping a percentage onto ref
+func scale(a arg, ref float64) float64 {
+	if a.percent {
+		return a.value / 100 * ref
+	}
+	return a.value
+}
+
+// channel converts 0 to 1 into a byte, clamping out-of-gamut values
+func channel(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+func cosDeg(d float64) float64 { return math.Cos(d * math.Pi / 180) }
+func sinDeg(d float64) float64 { return math.Sin(d * math.Pi / 180) }
@@ -0,0 +1,93 @@
+package color
+
+import "math"
+
+// hslToRGB converts hue in degrees and saturation and lightness from 0 to 1
+func hslToRGB(h, s, l float64) (float64, float64, float64) {
+	s, l = clamp01(s), clamp01(l)
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	f := func(n float64) float64 {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		return l - a*math.Max(-1, math.Min(k-3, math.Min(9-k, 1)))
+	}
+	return f(0), f(8), f(4)
+}
+
+// hwbToRGB converts hue in degrees and whiteness and blackness from 0 to 1
+func hwbToRGB(h, w, b float64) (float64, float64, float64) {
+	w, b = clamp01(w), clamp01(b)
+	if w+b >= 1 {
+		gray := w / (w + b)
+		return gray, gray, gray
+	}
+	r, g, bl := hslToRGB(h, 1, 0.5)
+	k := 1 - w - b
+	return r*k + w, g*k + w, bl*k + w
+}
+
+// labToRGB converts CIE Lab (D50 white) to gamma-encoded sRGB
+func labToRGB(l, a, b float64) (float64, float64, float64) {
+	const kappa, epsilon = 24389.0 / 27, 216.0 / 24389
+	fy := (l + 16) / 116
+	fx := fy + a/500
+	fz := fy - b/200
+	inv := func(f float64) float64 {
+		if f*f*f > epsilon {
+			return f * f * f
+		}
+		return (116*f - 16) / kappa
+	}
+	var y float64
+	if l > kappa*epsilon {
+		y = fy * fy * fy
+	} else {
+		y = l / kappa
+	}
+	// D50 reference white
+	x, z := inv(fx)*0.3457/0.3585, inv(fz)*(1-0.3457-0.3585)/0.3585
+
+	// Bradford adaptation from D50 to D65
+	x, y, z = 0.955473421488075*x-0.02309845494876471*y+0.06325924320057072*z,
+		-0.0283697093338637*x+1.0099953980813041*y+0.021041441191917323*z,
+		0.012314014864481998*x-0.020507649298898964*y+1.330365926242124*z
+
+	return xyzToRGB(x, y, z)
+}
+
+// oklabToRGB converts OKLab to gamma-encoded sRGB
+func oklabToRGB(l, a, b float64) (float64, float64, float64) {
+	lp := l + 0.3963377774*a + 0.2158037573*b
+	mp := l - 0.1055613458*a - 0.0638541728*b
+	sp := l - 0.0894841775*a - 1.2914855480*b
+	lc, mc, sc := lp*lp*lp, mp*mp*mp, sp*sp*sp
+	return gamma(4.0767416621*lc - 3.3077115913*mc + 0.2309699292*sc),
+		gamma(-1.2684380046*lc + 2.6097574011*mc - 0.3413193965*sc),
+		gamma(-0.0041960863*lc - 0.7034186147*mc + 1.7076147010*sc)
+}
+
+// xyzToRGB converts CIE XYZ (D65 white) to gamma-encoded sRGB
+func xyzToRGB(x, y, z float64) (float64, float64, float64) {
+	return gamma(3.2409699419045226*x - 1.537383177570094*y - 0.4986107602930034*z),
+		gamma(-0.9692436362808796*x + 1.8759675015077202*y + 0.04155505740717559*z),
+		gamma(0.05563007969699366*x - 0.20397695888897652*y + 1.0569715142428786*z)
+}
+
+// gamma applies the sRGB transfer function to a linear channel
+func gamma(v float64) float64 {
+	sign := 1.0
+	if v < 0 {
+		sign, v = -1, -v
+	}
+	if v > 0.0031308 {
+		return sign * (1.055*math.Pow(v, 1/2.4) - 0.055)
+	}
+	return sign * 12.92 * v
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
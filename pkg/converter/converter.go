@@ -1,7 +1,12 @@
 package converter
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
@@ -9,161 +14,179 @@ import (
 	"github.com/prabinpanta0/VectorFormatBridge/pkg/transform"
 )
 
-// SVGToEGF converts an SVG file to EGF format
-func SVGToEGF(svgFile string, egfFile string) error {
-	svgData, err := svg.ParseSVG(svgFile)
+// DecodeSVG reads an SVG document from r and maps it onto an EGF document
+func DecodeSVG(r io.Reader) (*egf.Document, error) {
+	svgData, err := svg.Decode(r)
 	if err != nil {
-		return fmt.Errorf("failed to parse SVG: %w", err)
+		return nil, fmt.Errorf("failed to parse SVG: %w", err)
 	}
 
-	egfContent := fmt.Sprintf("M(%s,%s,#fff)\n", svgData.Width, svgData.Height)
+	return buildDocument(svgData)
+}
 
-	entityMap := map[string]string{}
-	entityCount := 1
+// EncodeSVG renders an EGF document as SVG and writes it to w
+func EncodeSVG(w io.Writer, doc *egf.Document) error {
+	width, height := "800", "600"
+	body := ""
 
-	// Helper to handle entities
-	addEntity := func(def string) string {
-		for k, v := range entityMap {
-			if v == def {
-				return k // reuse
-			}
-		}
-		id := fmt.Sprintf("#%02d", entityCount)
-		entityCount++
-		entityMap[id] = def
-		return id
-	}
+	sc := &scene{entities: make(map[string]egf.Shape), styles: make(map[string]*egf.Style)}
 
-	// Process basic elements
-	for _, r := range svgData.Rects {
-		cmd := fmt.Sprintf("R(%s,%s,%s,%s) S(%s,%s)", r.X, r.Y, r.Width, r.Height, colorOrDefault(r.Stroke, "#000"), colorOrDefault(r.Fill, "#none"))
-		id := addEntity(cmd)
-		egfContent += fmt.Sprintf("CALL%s T(0,0,1,0)\n", id)
-	}
+	for _, n := range doc.Nodes {
+		switch n := n.(type) {
+		case *egf.Canvas:
+			width, height = egf.FormatNumber(n.Width), egf.FormatNumber(n.Height)
 
-	for _, c := range svgData.Circles {
-		cmd := fmt.Sprintf("C(%s,%s,%s) S(%s,%s)", c.Cx, c.Cy, c.R, colorOrDefault(c.Stroke, "#000"), colorOrDefault(c.Fill, "#none"))
-		id := addEntity(cmd)
-		egfContent += fmt.Sprintf("CALL%s T(0,0,1,0)\n", id)
-	}
+		case *egf.EntityDef:
+			sc.entities[n.ID] = n.Shape
 
-	for _, l := range svgData.Lines {
-		cmd := fmt.Sprintf("L(%s,%s,%s,%s) S(%s)", l.X1, l.Y1, l.X2, l.Y2, colorOrDefault(l.Stroke, "#000"))
-		id := addEntity(cmd)
-		egfContent += fmt.Sprintf("CALL%s T(0,0,1,0)\n", id)
-	}
-
-	for _, p := range svgData.Paths {
-		cmd := fmt.Sprintf("P[%s] S(%s,%s)", sanitizePath(p.D), colorOrDefault(p.Stroke, "#000"), colorOrDefault(p.Fill, "#none"))
-		id := addEntity(cmd)
-		egfContent += fmt.Sprintf("CALL%s T(0,0,1,0)\n", id)
-	}
+		case *egf.StyleDef:
+			sc.styles[n.Name] = n.Style
 
-	for _, e := range svgData.Ellipses {
-		cmd := fmt.Sprintf("E(%s,%s,%s,%s) S(%s,%s)", e.Cx, e.Cy, e.Rx, e.Ry, colorOrDefault(e.Stroke, "#000"), colorOrDefault(e.Fill, "#none"))
-		id := addEntity(cmd)
-		egfContent += fmt.Sprintf("CALL%s T(0,0,1,0)\n", id)
-	}
+		case *egf.Call:
+			content, err := renderCall(n, transform.Identity(), sc, nil)
+			if err != nil {
+				return err
+			}
+			body += content + "\n"
 
-	for _, poly := range svgData.Polygons {
-		cmd := fmt.Sprintf("PG[%s] S(%s,%s)", sanitizePoints(poly.Points), colorOrDefault(poly.Stroke, "#000"), colorOrDefault(poly.Fill, "#none"))
-		id := addEntity(cmd)
-		egfContent += fmt.Sprintf("CALL%s T(0,0,1,0)\n", id)
-	}
+		case *egf.Comment:
+			// Comments are not carried into SVG output
 
-	for _, pl := range svgData.Polylines {
-		cmd := fmt.Sprintf("PL[%s] S(%s)", sanitizePoints(pl.Points), colorOrDefault(pl.Stroke, "#000"))
-		id := addEntity(cmd)
-		egfContent += fmt.Sprintf("CALL%s T(0,0,1,0)\n", id)
+		case egf.Shape:
+			content, err := renderShape(n, transform.Identity(), sc, nil)
+			if err != nil {
+				return err
+			}
+			body += content + "\n"
+		}
 	}
 
-	// Write entities at the top
-	entityDefs := ""
-	for id, def := range entityMap {
-		entityDefs += fmt.Sprintf("H%s = %s\n", id, def)
-	}
-	egfContent = entityDefs + egfContent
+	svgContent := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s">`, width, height) + "\n"
+	svgContent += body + "</svg>"
 
-	return egf.WriteEGF(egfFile, egfContent)
+	_, err := io.WriteString(w, svgContent)
+	return err
 }
 
-// EGFToSVG converts an EGF file to SVG format
-func EGFToSVG(egfFile string, svgFile string) error {
-	egfContent, err := egf.ReadEGF(egfFile)
-	if err != nil {
-		return fmt.Errorf("failed to read EGF: %w", err)
-	}
-
-	lines := strings.Split(egfContent, "\n")
-	svgContent := `<svg xmlns="http://www.w3.org/2000/svg" width="800" height="600">` + "\n"
+// ConvertSVGToEGF reads SVG from r and writes canonical EGF to w
+func ConvertSVGToEGF(w io.Writer, r io.Reader) error {
+	return ConvertWith(w, r, DecoderFunc(DecodeSVG), EncoderFunc(egf.WriteDocument))
+}
 
-	entityMap := make(map[string]string) // H# entities
+// ConvertEGFToSVG reads EGF from r and writes SVG to w
+func ConvertEGFToSVG(w io.Writer, r io.Reader) error {
+	return ConvertWith(w, r, DecoderFunc(decodeEGF), EncoderFunc(EncodeSVG))
+}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if len(line) == 0 {
-			continue
-		}
+// ConvertEGFToEGFB reads EGF from r and writes EGFB to w with the given
+// precision, checksum and compression options
+func ConvertEGFToEGFB(w io.Writer, r io.Reader, opts egf.EncodeOptions) error {
+	return ConvertWith(w, r, DecoderFunc(decodeEGF), EGFBEncoder{Options: opts})
+}
 
-		switch {
-		case strings.HasPrefix(line, "M("):
-			size := extractParams(line)
-			if len(size) >= 2 {
-				svgContent = fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s">`, size[0], size[1]) + "\n"
-			}
+// ConvertEGFBToEGF reads EGFB from r and writes canonical EGF to w,
+// returning the warnings for data dropped in lenient mode
+func ConvertEGFBToEGF(w io.Writer, r io.Reader, opts egf.DecodeOptions) ([]*egf.DecodeError, error) {
+	var warnings []*egf.DecodeError
+	dec := EGFBDecoder{Options: opts, Warn: func(w *egf.DecodeError) {
+		warnings = append(warnings, w)
+	}}
+	if err := ConvertWith(w, r, dec, EncoderFunc(egf.WriteDocument)); err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
 
-		case strings.HasPrefix(line, "H#"):
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				id := strings.TrimSpace(strings.TrimPrefix(parts[0], "H"))
-				entityMap[id] = strings.TrimSpace(parts[1])
-			}
+// SVGToEGFBytes converts an in-memory SVG document to EGF
+func SVGToEGFBytes(data []byte) ([]byte, error) {
+	return convertBytes(data, ConvertSVGToEGF)
+}
 
-		case strings.HasPrefix(line, "CALL#"):
-			parts := strings.SplitN(line, " ", 2)
-			id := strings.TrimPrefix(parts[0], "CALL")
-			entity, exists := entityMap[id]
-			if exists {
-				t := transform.NewTransform()
-				if len(parts) > 1 {
-					t = transform.ParseTransform(parts[1])
-				}
-				content := renderEntity(entity, t)
-				svgContent += content + "\n"
-			}
+// EGFToSVGBytes converts in-memory EGF to SVG
+func EGFToSVGBytes(data []byte) ([]byte, error) {
+	return convertBytes(data, ConvertEGFToSVG)
+}
 
-		case strings.HasPrefix(line, "G["):
-			content := extractGroupContent(line)
-			svgContent += content + "\n"
+// EGFToEGFBBytes converts in-memory EGF to EGFB
+func EGFToEGFBBytes(data []byte, opts egf.EncodeOptions) ([]byte, error) {
+	return convertBytes(data, func(w io.Writer, r io.Reader) error {
+		return ConvertEGFToEGFB(w, r, opts)
+	})
+}
 
-		default:
-			svgContent += renderLine(line, transform.NewTransform()) + "\n"
-		}
-	}
+// EGFBToEGFBytes converts in-memory EGFB to EGF
+func EGFBToEGFBytes(data []byte, opts egf.DecodeOptions) ([]byte, []*egf.DecodeError, error) {
+	var warnings []*egf.DecodeError
+	out, err := convertBytes(data, func(w io.Writer, r io.Reader) (err error) {
+		warnings, err = ConvertEGFBToEGF(w, r, opts)
+		return err
+	})
+	return out, warnings, err
+}
 
-	svgContent += "</svg>"
+// SVGToEGF converts an SVG file to EGF format
+func SVGToEGF(svgFile string, egfFile string) error {
+	return convertFile(svgFile, egfFile, ConvertSVGToEGF)
+}
 
-	return svg.WriteSVG(svgFile, svgContent)
+// EGFToSVG converts an EGF file to SVG format
+func EGFToSVG(egfFile string, svgFile string) error {
+	return convertFile(egfFile, svgFile, ConvertEGFToSVG)
 }
 
 // EGFToEGFB converts EGF to binary EGFB format
 func EGFToEGFB(egfFile string, egfbFile string) error {
-	egfContent, err := egf.ReadEGF(egfFile)
-	if err != nil {
-		return fmt.Errorf("failed to read EGF: %w", err)
-	}
+	return EGFToEGFBWithOptions(egfFile, egfbFile, egf.EncodeOptions{})
+}
 
-	return egf.EncodeToEGFB(egfContent, egfbFile)
+// EGFToEGFBWithOptions converts EGF to binary EGFB format with the given
+// precision, checksum and compression options
+func EGFToEGFBWithOptions(egfFile string, egfbFile string, opts egf.EncodeOptions) error {
+	return convertFile(egfFile, egfbFile, func(w io.Writer, r io.Reader) error {
+		return ConvertEGFToEGFB(w, r, opts)
+	})
 }
 
 // EGFBToEGF converts binary EGFB to EGF format
 func EGFBToEGF(egfbFile string, egfFile string) error {
-	egfContent, err := egf.DecodeFromEGFB(egfbFile)
+	_, err := EGFBToEGFWithOptions(egfbFile, egfFile, egf.DecodeOptions{})
+	return err
+}
+
+// EGFBToEGFWithOptions converts binary EGFB to EGF format, returning the
+// warnings for data dropped in lenient mode
+func EGFBToEGFWithOptions(egfbFile string, egfFile string, opts egf.DecodeOptions) ([]*egf.DecodeError, error) {
+	var warnings []*egf.DecodeError
+	err := convertFile(egfbFile, egfFile, func(w io.Writer, r io.Reader) (err error) {
+		warnings, err = ConvertEGFBToEGF(w, r, opts)
+		return err
+	})
+	return warnings, err
+}
+
+// convertBytes runs a streaming conversion over in-memory data
+func convertBytes(data []byte, convert func(io.Writer, io.Reader) error) ([]byte, error) {
+	var out bytes.Buffer
+	if err := convert(&out, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// convertFile runs a streaming conversion between two files. The output
+// file is only written once the whole conversion has succeeded.
+func convertFile(inFile string, outFile string, convert func(io.Writer, io.Reader) error) error {
+	in, err := os.Open(inFile)
 	if err != nil {
-		return fmt.Errorf("failed to decode EGFB: %w", err)
+		return err
 	}
+	defer in.Close()
 
-	return egf.WriteEGF(egfFile, egfContent)
+	var out bytes.Buffer
+	if err := convert(&out, in); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outFile, out.Bytes(), 0644)
 }
 
 // Helper functions
@@ -176,12 +199,12 @@ func colorOrDefault(color, defaultColor string) string {
 	return color
 }
 
-// sanitizePath cleans up SVG path data for EGF format
-func sanitizePath(path string) string {
-	return strings.TrimSpace(strings.ReplaceAll(path, "  ", " "))
-}
-
-// sanitizePoints cleans up SVG points data for EGF format
-func sanitizePoints(points string) string {
-	return strings.TrimSpace(strings.ReplaceAll(points, "  ", " "))
+// parseLength parses an SVG length such as "12" or "12px". Unparseable
+// values (including percentages and missing attributes) yield 0.
+func parseLength(s string) float64 {
+	val, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+	if err != nil {
+		return 0
+	}
+	return val
 }
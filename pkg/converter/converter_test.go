@@ -0,0 +1,151 @@
+package converter
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/svg"
+)
+
+// paintOrder lists the element types of an SVG document in document order,
+// descending into groups
+func paintOrder(t *testing.T, els []svg.Element) []string {
+	t.Helper()
+	var order []string
+	for _, el := range els {
+		switch el := el.(type) {
+		case *svg.Group:
+			order = append(order, paintOrder(t, el.Children)...)
+		case *svg.Rect:
+			order = append(order, "rect")
+		case *svg.Circle:
+			order = append(order, "circle")
+		case *svg.Line:
+			order = append(order, "line")
+		case *svg.Ellipse:
+			order = append(order, "ellipse")
+		case *svg.Polygon:
+			order = append(order, "polygon")
+		case *svg.Polyline:
+			order = append(order, "polyline")
+		case *svg.Path:
+			order = append(order, "path")
+		}
+	}
+	return order
+}
+
+func TestRoundTripKeepsPaintOrder(t *testing.T) {
+	input := []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100">
+  <circle cx="20" cy="20" r="10" fill="red"/>
+  <rect x="10" y="10" width="30" height="30" fill="blue"/>
+  <g transform="translate(5,5)">
+    <line x1="0" y1="0" x2="10" y2="10" stroke="black"/>
+    <circle cx="50" cy="50" r="5"/>
+  </g>
+  <polygon points="0,0 10,0 10,10" fill="green"/>
+  <rect x="60" y="60" width="10" height="10"/>
+</svg>`)
+
+	in, err := svg.Decode(bytes.NewReader(input))
+	if err != nil {
+		t.Fatalf("decoding input: %v", err)
+	}
+	want := paintOrder(t, in.Children)
+
+	egfData, err := SVGToEGFBytes(input)
+	if err != nil {
+		t.Fatalf("svg2egf: %v", err)
+	}
+	output, err := EGFToSVGBytes(egfData)
+	if err != nil {
+		t.Fatalf("egf2svg: %v\n%s", err, egfData)
+	}
+	out, err := svg.Decode(bytes.NewReader(output))
+	if err != nil {
+		t.Fatalf("decoding output: %v\n%s", err, output)
+	}
+
+	if got := paintOrder(t, out.Children); !reflect.DeepEqual(got, want) {
+		t.Errorf("paint order changed\n got: %v\nwant: %v\nEGF:\n%s", got, want, egfData)
+	}
+}
+
+func TestStrokeScalesWithTransform(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      string
+		width     string
+		dash      string
+		transform bool
+	}{
+		{"baked scale", "CALL#01 T(10,10,2,0)", "4", "8,4", false},
+		{"rotated scale kept as attribute", "CALL#01 T(10,10,2,45)", "2", "4,2", true},
+		{"non-uniform scale kept as attribute", "CALL#01 T(10,10,2,1,0)", "2", "4,2", true},
+		{"translation only", "CALL#01 T(10,10,1,0)", "2", "4,2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "M(100,100)\nH#01 = R(0,0,10,10) S(#000,none,w=2,dash=(4,2))\n" + tt.call + "\n"
+			output, err := EGFToSVGBytes([]byte(src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, err := svg.Decode(bytes.NewReader(output))
+			if err != nil {
+				t.Fatalf("%v\n%s", err, output)
+			}
+			if len(doc.Children) != 1 {
+				t.Fatalf("got %d elements, want 1\n%s", len(doc.Children), output)
+			}
+			a := doc.Children[0].Common()
+			if got := a.Property("stroke-width"); got != tt.width {
+				t.Errorf("stroke-width = %q, want %q\n%s", got, tt.width, output)
+			}
+			if got := a.Property("stroke-dasharray"); got != tt.dash {
+				t.Errorf("stroke-dasharray = %q, want %q\n%s", got, tt.dash, output)
+			}
+			if got := a.Transform != ""; got != tt.transform {
+				t.Errorf("transform attribute = %q, want one: %v\n%s", a.Transform, tt.transform, output)
+			}
+		})
+	}
+}
+
+func TestGroupStyleLifted(t *testing.T) {
+	tests := []struct {
+		name  string
+		group string
+		want  string
+	}{
+		{"shared", `<g fill="red"><rect width="5" height="5"/><circle r="3"/></g>`, "M(100,100,#fff)\nH#01 = R(0,0,5,5)\nH#02 = C(0,0,3)\nH#03 = G[\n  CALL#01 T(0,0,1,0)\n  CALL#02 T(0,0,1,0)\n] S(none,#f00)\nCALL#03 T(0,0,1,0)\n"},
+		{"differing", `<g fill="red"><rect width="5" height="5"/><circle r="3" fill="blue"/></g>`, "M(100,100,#fff)\nH#01 = R(0,0,5,5) S(none,#f00)\nH#02 = C(0,0,3) S(none,#00f)\nH#03 = G[\n  CALL#01 T(0,0,1,0)\n  CALL#02 T(0,0,1,0)\n]\nCALL#03 T(0,0,1,0)\n"},
+		{"single child", `<g fill="red"><rect width="5" height="5"/></g>`, "M(100,100,#fff)\nH#01 = R(0,0,5,5) S(none,#f00)\nH#02 = G[\n  CALL#01 T(0,0,1,0)\n]\nCALL#02 T(0,0,1,0)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100">` + tt.group + `</svg>`
+			output, err := SVGToEGFBytes([]byte(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(output); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformedCoordinatesKeepPrecision(t *testing.T) {
+	src := "M(100,100)\nH#01 = P[M 0.125 0 L 1 0.001] S(#000)\nH#02 = PG[0.125,0 1,0.001 0,1] S(#000,#fff)\nCALL#01 T(0.5,0,1,0)\nCALL#02 T(0.5,0,1,0)\n"
+	output, err := EGFToSVGBytes([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`d="M 0.625 0 L 1.5 0.001"`, `points="0.625000,0.000000 1.500000,0.001000 0.500000,1.000000"`} {
+		if !bytes.Contains(output, []byte(want)) {
+			t.Errorf("output lacks %s\n%s", want, output)
+		}
+	}
+}
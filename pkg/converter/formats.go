@@ -0,0 +1,122 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
+)
+
+func init() {
+	Register(Format{
+		Name:       "svg",
+		Extensions: []string{".svg"},
+		Detect:     isSVG,
+		Decoder:    DecoderFunc(DecodeSVG),
+		Encoder:    EncoderFunc(EncodeSVG),
+	})
+	Register(Format{
+		Name:       "egf",
+		Extensions: []string{".egf"},
+		Detect:     isEGF,
+		Decoder:    DecoderFunc(decodeEGF),
+		Encoder:    EncoderFunc(egf.WriteDocument),
+	})
+	Register(Format{
+		Name:       "egfb",
+		Extensions: []string{".egfb"},
+		Detect:     isEGFB,
+		Decoder:    EGFBDecoder{},
+		Encoder:    EGFBEncoder{},
+	})
+}
+
+// isSVG reports whether data opens with an XML prolog or an <svg> element,
+// allowing for a byte order mark, whitespace and leading comments
+func isSVG(data []byte) bool {
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if bytes.HasPrefix(data, []byte("<?xml")) || bytes.HasPrefix(data, []byte("<svg")) {
+		return true
+	}
+	// Comments or a doctype may precede the root element
+	return bytes.HasPrefix(data, []byte("<!")) && bytes.Contains(data, []byte("<svg"))
+}
+
+// egfCommands are the prefixes an EGF statement can start with
+var egfCommands = [][]byte{
+	[]byte("M("), []byte("H#"), []byte("S#"), []byte("CALL#"), []byte("G["),
+	[]byte("R("), []byte("C("), []byte("L("), []byte("E("),
+	[]byte("P["), []byte("PG["), []byte("PL["),
+}
+
+// isEGF reports whether the first statement in data starts with an EGF
+// command, skipping blank lines and comments. The canvas is optional, so
+// any command counts; a statement that is cut short or malformed is still
+// detected and left for the parser to report.
+func isEGF(data []byte) bool {
+	for len(data) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			line, data = data, nil
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		for _, cmd := range egfCommands {
+			if bytes.HasPrefix(line, cmd) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// isEGFB reports whether data starts with the EGFB magic
+func isEGFB(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("EGFB"))
+}
+
+func decodeEGF(r io.Reader) (*egf.Document, error) {
+	doc, err := egf.ParseReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read EGF: %w", err)
+	}
+	return doc, nil
+}
+
+// EGFBDecoder reads EGFB with the given options. Warn, if set, receives
+// the warnings produced in lenient mode.
+type EGFBDecoder struct {
+	Options egf.DecodeOptions
+	Warn    func(*egf.DecodeError)
+}
+
+// Decode reads an EGFB document from r
+func (d EGFBDecoder) Decode(r io.Reader) (*egf.Document, error) {
+	doc, warnings, err := egf.ReadEGFB(r, d.Options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode EGFB: %w", err)
+	}
+	if d.Warn != nil {
+		for _, w := range warnings {
+			d.Warn(w)
+		}
+	}
+	return doc, nil
+}
+
+// EGFBEncoder writes EGFB with the given precision, checksum and
+// compression options
+type EGFBEncoder struct {
+	Options egf.EncodeOptions
+}
+
+// Encode writes doc to w as EGFB
+func (e EGFBEncoder) Encode(w io.Writer, doc *egf.Document) error {
+	return egf.WriteEGFB(w, doc, e.Options)
+}
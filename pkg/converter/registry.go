@@ -0,0 +1,171 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
+)
+
+// Decoder reads a vector format into the EGF document model
+type Decoder interface {
+	Decode(r io.Reader) (*egf.Document, error)
+}
+
+// Encoder writes the EGF document model out in a vector format
+type Encoder interface {
+	Encode(w io.Writer, doc *egf.Document) error
+}
+
+// DecoderFunc adapts a function to the Decoder interface
+type DecoderFunc func(r io.Reader) (*egf.Document, error)
+
+// Decode calls f(r)
+func (f DecoderFunc) Decode(r io.Reader) (*egf.Document, error) {
+	return f(r)
+}
+
+// EncoderFunc adapts a function to the Encoder interface
+type EncoderFunc func(w io.Writer, doc *egf.Document) error
+
+// Encode calls f(w, doc)
+func (f EncoderFunc) Encode(w io.Writer, doc *egf.Document) error {
+	return f(w, doc)
+}
+
+// Format is a registered vector format. Either Decoder or Encoder may be
+// nil for formats that can only be read or only be written.
+type Format struct {
+	// Name identifies the format on the command line, e.g. "svg"
+	Name string
+	// Extensions lists the file extensions used for the format, with dots
+	Extensions []string
+	// Detect reports whether data starts like a document in this format.
+	// It may be nil for formats that cannot be recognized by content.
+	Detect  func(data []byte) bool
+	Decoder Decoder
+	Encoder Encoder
+}
+
+var formats = map[string]*Format{}
+
+// Register adds a format to the registry. It panics if the name is empty
+// or already registered.
+func Register(f Format) {
+	name := strings.ToLower(f.Name)
+	if name == "" {
+		panic("converter: Register format with empty name")
+	}
+	if _, dup := formats[name]; dup {
+		panic("converter: Register called twice for format " + name)
+	}
+	f.Name = name
+	formats[name] = &f
+}
+
+// Lookup returns the registered format with the given name, ignoring case
+func Lookup(name string) (*Format, bool) {
+	f, ok := formats[strings.ToLower(name)]
+	return f, ok
+}
+
+// Formats returns the names of all registered formats in sorted order
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DetectFormat returns the name of the registered format whose content
+// signature matches data
+func DetectFormat(data []byte) (string, bool) {
+	for _, name := range Formats() {
+		if f := formats[name]; f.Detect != nil && f.Detect(data) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// FormatForPath returns the name of the registered format using the file
+// extension of path
+func FormatForPath(path string) (string, bool) {
+	ext := filepath.Ext(path)
+	for _, name := range Formats() {
+		for _, e := range formats[name].Extensions {
+			if strings.EqualFold(e, ext) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Convert reads format from out of r and writes format to into w,
+// going through the EGF document model
+func Convert(w io.Writer, r io.Reader, from string, to string) error {
+	dec, err := DecoderFor(from)
+	if err != nil {
+		return err
+	}
+	enc, err := EncoderFor(to)
+	if err != nil {
+		return err
+	}
+	return ConvertWith(w, r, dec, enc)
+}
+
+// ConvertWith decodes r with dec and encodes the document to w with enc
+func ConvertWith(w io.Writer, r io.Reader, dec Decoder, enc Encoder) error {
+	doc, err := dec.Decode(r)
+	if err != nil {
+		return err
+	}
+	return enc.Encode(w, doc)
+}
+
+// ConvertFile converts inFile to outFile with dec and enc. The output file
+// is only written once the whole conversion has succeeded.
+func ConvertFile(inFile string, outFile string, dec Decoder, enc Encoder) error {
+	return convertFile(inFile, outFile, func(w io.Writer, r io.Reader) error {
+		return ConvertWith(w, r, dec, enc)
+	})
+}
+
+// DecoderFor returns the decoder of a registered format
+func DecoderFor(name string) (Decoder, error) {
+	f, err := lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if f.Decoder == nil {
+		return nil, fmt.Errorf("format %s cannot be read", f.Name)
+	}
+	return f.Decoder, nil
+}
+
+// EncoderFor returns the encoder of a registered format
+func EncoderFor(name string) (Encoder, error) {
+	f, err := lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if f.Encoder == nil {
+		return nil, fmt.Errorf("format %s cannot be written", f.Name)
+	}
+	return f.Encoder, nil
+}
+
+func lookup(name string) (*Format, error) {
+	f, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (known formats: %s)", name, strings.Join(Formats(), ", "))
+	}
+	return f, nil
+}
@@ -0,0 +1,413 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/color"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/pathdata"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/svg"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/transform"
+)
+
+// presentation holds the SVG presentation properties inherited from
+// ancestor groups, keyed by property name
+type presentation map[string]string
+
+// styleProperties are the inherited presentation properties with an EGF
+// style equivalent, after color, which currentColor refers to
+var styleProperties = []string{
+	"color", "fill", "stroke", "stroke-width", "stroke-opacity", "fill-opacity",
+	"stroke-dasharray", "stroke-linecap", "stroke-linejoin", "stroke-miterlimit", "fill-rule",
+}
+
+// inherit returns the properties in effect for an element, taking the
+// values it sets itself, from attributes, stylesheets or its style, over
+// inherited ones. As in CSS, an invalid value is ignored.
+func (p presentation) inherit(a *svg.Attrs) presentation {
+	out := make(presentation, len(styleProperties))
+	for k, v := range p {
+		out[k] = v
+	}
+	for _, name := range styleProperties {
+		v := a.Property(name)
+		if v == "" || v == "inherit" {
+			continue
+		}
+		if name == "fill" || name == "stroke" || name == "color" {
+			if v = paint(v); v == "" {
+				continue
+			}
+		} else if !setStyleProperty(&egf.Style{}, name, v) {
+			continue
+		}
+		out[name] = v
+	}
+	return out
+}
+
+// paint reduces an SVG paint to a canonical EGF color, or "" if it is
+// invalid. Paint servers such as gradients have no EGF equivalent, so a
+// url() paint becomes its fallback color, or none.
+func paint(value string) string {
+	if strings.HasPrefix(value, "url(") {
+		end := strings.IndexByte(value, ')')
+		if end == -1 {
+			return ""
+		}
+		if value = strings.TrimSpace(value[end+1:]); value == "" {
+			return "none"
+		}
+	}
+	c, err := color.Normalize(value)
+	if err != nil {
+		return ""
+	}
+	return c
+}
+
+// paintOrDefault returns an inherited paint with currentColor resolved,
+// or the default when the paint is unset
+func (p presentation) paintOrDefault(name, defaultColor string) string {
+	v := p[name]
+	if v == "currentColor" && p["color"] != "" && p["color"] != "currentColor" {
+		v = p["color"]
+	}
+	return colorOrDefault(v, defaultColor)
+}
+
+// style resolves the EGF style. Unset paints take their SVG initial
+// values: no stroke and a black fill.
+func (p presentation) style() *egf.Style {
+	s := &egf.Style{Stroke: p.paintOrDefault("stroke", "none"), Fill: p.paintOrDefault("fill", "#000")}
+	for _, name := range styleProperties[3:] {
+		setStyleProperty(s, name, p[name])
+	}
+	return s
+}
+
+// strokeStyle resolves a stroke-only EGF style for lines, which have no
+// interior to fill
+func (p presentation) strokeStyle() *egf.Style {
+	s := &egf.Style{Stroke: p.paintOrDefault("stroke", "none")}
+	for _, name := range styleProperties[3:] {
+		if !strings.HasPrefix(name, "fill-") {
+			setStyleProperty(s, name, p[name])
+		}
+	}
+	return s
+}
+
+// setStyleProperty sets the style field for an SVG stroke or fill property,
+// reporting whether the value was valid
+func setStyleProperty(s *egf.Style, name, value string) bool {
+	if value == "" {
+		return false
+	}
+	number := func(field **float64, min, max float64) bool {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(value, "px"), 64)
+		if err != nil || v < min {
+			return false
+		}
+		if v > max {
+			v = max
+		}
+		*field = &v
+		return true
+	}
+	keyword := func(field *string, values ...string) bool {
+		for _, v := range values {
+			if value == v {
+				*field = v
+				return true
+			}
+		}
+		return false
+	}
+	switch name {
+	case "stroke-width":
+		return number(&s.Width, 0, math.Inf(1))
+	case "stroke-opacity", "fill-opacity":
+		field := &s.StrokeOpacity
+		if name == "fill-opacity" {
+			field = &s.FillOpacity
+		}
+		if strings.HasSuffix(value, "%") {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil {
+				return false
+			}
+			value = strconv.FormatFloat(v/100, 'f', -1, 64)
+		}
+		// Opacities outside the range are clamped, as in CSS
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v < 0 {
+			value = "0"
+		}
+		return number(field, 0, 1)
+	case "stroke-dasharray":
+		if value == "none" {
+			s.Dash = nil
+			return true
+		}
+		var dash []float64
+		for _, f := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(f, "px"), 64)
+			if err != nil || v < 0 {
+				return false
+			}
+			dash = append(dash, v)
+		}
+		s.Dash = dash
+		return len(dash) > 0
+	case "stroke-linecap":
+		return keyword(&s.Cap, "butt", "round", "square")
+	case "stroke-linejoin":
+		return keyword(&s.Join, "miter", "round", "bevel")
+	case "stroke-miterlimit":
+		return number(&s.MiterLimit, 1, math.Inf(1))
+	case "fill-rule":
+		return keyword(&s.FillRule, "nonzero", "evenodd")
+	}
+	return false
+}
+
+// docBuilder accumulates H# entity definitions while mapping SVG elements.
+// Each distinct shape becomes one entity, numbered in first-use order, so
+// output is deterministic and repeated shapes share one definition.
+type docBuilder struct {
+	defs []egf.Node
+	ids  map[string]string
+}
+
+// buildDocument maps parsed SVG elements onto an EGF document
+func buildDocument(svgData *svg.SVG) (*egf.Document, error) {
+	b := &docBuilder{ids: map[string]string{}}
+
+	calls, err := b.elements(svgData.Children, presentation(nil).inherit(&svgData.Attrs))
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := canvasSize(svgData)
+	canvas := &egf.Canvas{Width: width, Height: height, Background: "#fff"}
+
+	doc := &egf.Document{Nodes: []egf.Node{canvas}}
+	doc.Nodes = append(doc.Nodes, b.factorStyles()...)
+	doc.Nodes = append(doc.Nodes, b.defs...)
+	doc.Nodes = append(doc.Nodes, calls...)
+	return doc, nil
+}
+
+// entity returns a CALL to the entity for shape, defining it on first use
+func (b *docBuilder) entity(shape egf.Shape, m transform.Matrix) *egf.Call {
+	key := egf.FormatNode(shape)
+	id, exists := b.ids[key]
+	if !exists {
+		id = fmt.Sprintf("%02d", len(b.defs)+1)
+		b.ids[key] = id
+		b.defs = append(b.defs, &egf.EntityDef{ID: id, Shape: shape})
+	}
+	return &egf.Call{ID: id, Transform: m}
+}
+
+// factorStyles replaces every style used by more than one entity with a
+// reference to a named style, returning the S# definitions. Styles are
+// named s1, s2, ... in first-use order.
+func (b *docBuilder) factorStyles() []egf.Node {
+	counts := map[string]int{}
+	for _, def := range b.defs {
+		if style := egf.StyleOf(def.(*egf.EntityDef).Shape); style != nil {
+			counts[egf.FormatStyle(style)]++
+		}
+	}
+
+	var styleDefs []egf.Node
+	refs := map[string]*egf.Style{}
+	for _, def := range b.defs {
+		shape := def.(*egf.EntityDef).Shape
+		style := egf.StyleOf(shape)
+		if style == nil {
+			continue
+		}
+		key := egf.FormatStyle(style)
+		if counts[key] < 2 {
+			continue
+		}
+		ref, ok := refs[key]
+		if !ok {
+			ref = &egf.Style{Ref: fmt.Sprintf("s%d", len(styleDefs)+1)}
+			refs[key] = ref
+			styleDefs = append(styleDefs, &egf.StyleDef{Name: ref.Ref, Style: style})
+		}
+		egf.SetStyle(shape, ref)
+	}
+	return styleDefs
+}
+
+// placedShape is a mapped SVG element with the transform of its CALL
+type placedShape struct {
+	shape egf.Shape
+	m     transform.Matrix
+}
+
+// elements maps SVG elements in document order, preserving paint order,
+// and returns a CALL to the entity for each
+func (b *docBuilder) elements(els []svg.Element, inherited presentation) ([]egf.Node, error) {
+	shapes, err := b.shapes(els, inherited)
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]egf.Node, len(shapes))
+	for i, s := range shapes {
+		calls[i] = b.entity(s.shape, s.m)
+	}
+	return calls, nil
+}
+
+// shapes maps SVG elements in document order. Groups become G[...]
+// entities whose bodies call their children's entities. Each element's
+// transform attribute becomes the transform of its CALL, so transforms
+// compose through group nesting exactly as in SVG.
+func (b *docBuilder) shapes(els []svg.Element, inherited presentation) ([]placedShape, error) {
+	var shapes []placedShape
+	for _, el := range els {
+		if _, ok := el.(*svg.StyleSheet); ok {
+			continue // applied when the SVG was decoded
+		}
+		m, err := transform.ParseSVG(el.Common().Transform)
+		if err != nil {
+			return nil, err
+		}
+
+		if g, ok := el.(*svg.Group); ok {
+			children, err := b.shapes(g.Children, inherited.inherit(&g.Attrs))
+			if err != nil {
+				return nil, err
+			}
+			if len(children) == 0 {
+				continue // empty groups draw nothing
+			}
+			group := &egf.Group{Style: liftStyle(children)}
+			for _, c := range children {
+				group.Children = append(group.Children, b.entity(c.shape, c.m))
+			}
+			shapes = append(shapes, placedShape{group, m})
+			continue
+		}
+
+		shape, err := elementToShape(el, inherited)
+		if err != nil {
+			return nil, err
+		}
+		shapes = append(shapes, placedShape{shape, m})
+	}
+	return shapes, nil
+}
+
+// liftStyle moves a style shared by every child of a group onto the group,
+// which the children then inherit, and returns it. It returns nil, leaving
+// the children alone, for a single child or when their styles differ.
+func liftStyle(children []placedShape) *egf.Style {
+	if len(children) < 2 {
+		return nil
+	}
+	shared := egf.StyleOf(children[0].shape)
+	if shared == nil {
+		return nil
+	}
+	key := egf.FormatStyle(shared)
+	for _, c := range children[1:] {
+		if style := egf.StyleOf(c.shape); style == nil || egf.FormatStyle(style) != key {
+			return nil
+		}
+	}
+	for _, c := range children {
+		egf.SetStyle(c.shape, nil)
+	}
+	return shared
+}
+
+// elementToShape converts a single SVG element to the equivalent EGF shape
+func elementToShape(el svg.Element, inherited presentation) (egf.Shape, error) {
+	p := inherited.inherit(el.Common())
+	switch el := el.(type) {
+	case *svg.Rect:
+		return &egf.Rect{
+			X: parseLength(el.X), Y: parseLength(el.Y), Width: parseLength(el.Width), Height: parseLength(el.Height),
+			Style: p.style(),
+		}, nil
+
+	case *svg.Circle:
+		return &egf.Circle{
+			Cx: parseLength(el.Cx), Cy: parseLength(el.Cy), R: parseLength(el.R),
+			Style: p.style(),
+		}, nil
+
+	case *svg.Line:
+		return &egf.Line{
+			X1: parseLength(el.X1), Y1: parseLength(el.Y1), X2: parseLength(el.X2), Y2: parseLength(el.Y2),
+			Style: p.strokeStyle(),
+		}, nil
+
+	case *svg.Path:
+		data, err := pathdata.Parse(el.D)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path data: %w", err)
+		}
+		return &egf.Path{
+			Data:  data,
+			Style: p.style(),
+		}, nil
+
+	case *svg.Ellipse:
+		return &egf.Ellipse{
+			Cx: parseLength(el.Cx), Cy: parseLength(el.Cy), Rx: parseLength(el.Rx), Ry: parseLength(el.Ry),
+			Style: p.style(),
+		}, nil
+
+	case *svg.Polygon:
+		points, err := egf.ParsePoints(el.Points)
+		if err != nil {
+			return nil, fmt.Errorf("invalid polygon points: %w", err)
+		}
+		return &egf.Polygon{
+			Points: points,
+			Style:  p.style(),
+		}, nil
+
+	case *svg.Polyline:
+		points, err := egf.ParsePoints(el.Points)
+		if err != nil {
+			return nil, fmt.Errorf("invalid polyline points: %w", err)
+		}
+		return &egf.Polyline{
+			Points: points,
+			Style:  p.style(),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported SVG element %T", el)
+	}
+}
+
+// canvasSize returns the document size from width/height, falling back to
+// the viewBox dimensions when either is missing or relative
+func canvasSize(svgData *svg.SVG) (float64, float64) {
+	width, height := parseLength(svgData.Width), parseLength(svgData.Height)
+	if width != 0 && height != 0 {
+		return width, height
+	}
+	vb := strings.Fields(strings.ReplaceAll(svgData.ViewBox, ",", " "))
+	if len(vb) == 4 {
+		if width == 0 {
+			width = parseLength(vb[2])
+		}
+		if height == 0 {
+			height = parseLength(vb[3])
+		}
+	}
+	return width, height
+}
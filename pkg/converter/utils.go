@@ -2,188 +2,259 @@ package converter
 
 import (
 	"fmt"
-	"regexp"
-	"strconv"
+	"math"
 	"strings"
 
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/color"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/egf"
 	"github.com/prabinpanta0/VectorFormatBridge/pkg/transform"
 )
 
-// extractParams extracts parameters from a command line like "M(800,600,#fff)"
-func extractParams(line string) []string {
-	// Find content between parentheses
-	start := strings.Index(line, "(")
-	end := strings.Index(line, ")")
-	if start == -1 || end == -1 || start >= end {
-		return []string{}
-	}
-
-	content := line[start+1 : end]
-	parts := strings.Split(content, ",")
+// coordDecimals is the precision of transformed coordinates in SVG
+// output, matching the %f used for the other shapes' attributes
+const coordDecimals = 6
 
-	// Trim spaces from each part
-	for i := range parts {
-		parts[i] = strings.TrimSpace(parts[i])
+// styleAttrs converts an EGF style into SVG presentation attributes. An
+// EGF style without a fill, such as S(#000), is unfilled, so fill="none"
+// is written to override the SVG default of black. Translucent colors
+// become an opaque color and an opacity, combined with any opacity the
+// style sets.
+func styleAttrs(s *egf.Style) string {
+	if s == nil || s.Stroke == "" && s.Fill == "" {
+		return `stroke="black" fill="none"`
 	}
-
-	return parts
-}
-
-// parseF parses a string to float64
-func parseF(s string) float64 {
-	val, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return 0
+	if s.Fill == "" {
+		unfilled := *s
+		unfilled.Fill = "none"
+		s = &unfilled
 	}
-	return val
-}
 
-// extractStyle extracts style information from a line
-func extractStyle(line string) string {
-	// Look for S(...) pattern
-	re := regexp.MustCompile(`S\(([^)]+)\)`)
-	match := re.FindStringSubmatch(line)
-	if len(match) < 2 {
-		return `stroke="black" fill="none"`
+	var attrs []string
+	strokeOpacity, fillOpacity := s.StrokeOpacity, s.FillOpacity
+	paint := func(name, value string, opacity **float64) {
+		if value == "" {
+			return
+		}
+		c, err := color.Parse(value)
+		if err != nil {
+			// Unparseable colors can only come from documents built in
+			// code; pass them through for the renderer to judge
+			attrs = append(attrs, fmt.Sprintf(`%s="%s"`, name, value))
+			return
+		}
+		svgColor, alpha := c.SVG()
+		attrs = append(attrs, fmt.Sprintf(`%s="%s"`, name, svgColor))
+		if alpha < 1 {
+			combined := alpha
+			if *opacity != nil {
+				combined = math.Round(combined*(**opacity)*1000) / 1000
+			}
+			*opacity = &combined
+		}
 	}
+	paint("stroke", s.Stroke, &strokeOpacity)
+	paint("fill", s.Fill, &fillOpacity)
+	style := strings.Join(attrs, " ")
 
-	params := strings.Split(match[1], ",")
-	style := ""
-
-	if len(params) > 0 && strings.TrimSpace(params[0]) != "#none" {
-		style += fmt.Sprintf(`stroke="%s"`, strings.TrimSpace(params[0]))
+	number := func(name string, v *float64) {
+		if v != nil {
+			style += fmt.Sprintf(` %s="%s"`, name, egf.FormatNumber(*v))
+		}
 	}
-
-	if len(params) > 1 && strings.TrimSpace(params[1]) != "#none" {
-		if style != "" {
-			style += " "
+	keyword := func(name, v string) {
+		if v != "" {
+			style += fmt.Sprintf(` %s="%s"`, name, v)
 		}
-		style += fmt.Sprintf(`fill="%s"`, strings.TrimSpace(params[1]))
 	}
-
-	if style == "" {
-		style = `stroke="black" fill="none"`
+	number("stroke-width", s.Width)
+	number("stroke-opacity", strokeOpacity)
+	number("fill-opacity", fillOpacity)
+	if len(s.Dash) > 0 {
+		dash := make([]string, len(s.Dash))
+		for i, v := range s.Dash {
+			dash[i] = egf.FormatNumber(v)
+		}
+		keyword("stroke-dasharray", strings.Join(dash, ","))
 	}
+	keyword("stroke-linecap", s.Cap)
+	keyword("stroke-linejoin", s.Join)
+	number("stroke-miterlimit", s.MiterLimit)
+	keyword("fill-rule", s.FillRule)
 
 	return style
 }
 
-// extractPathData extracts path data from P[...] format
-func extractPathData(line string) string {
-	start := strings.Index(line, "[")
-	end := strings.LastIndex(line, "]")
-	if start == -1 || end == -1 || start >= end {
-		return ""
-	}
-	return line[start+1 : end]
-}
+// transformPoints applies transform to a list of points
+func transformPoints(points []egf.Point, m transform.Matrix) string {
+	var transformed []string
 
-// extractPointList extracts point list from PG[...] or PL[...] format
-func extractPointList(line string) string {
-	start := strings.Index(line, "[")
-	end := strings.LastIndex(line, "]")
-	if start == -1 || end == -1 || start >= end {
-		return ""
+	for _, p := range points {
+		newX, newY := m.Apply(p.X, p.Y)
+		transformed = append(transformed, fmt.Sprintf("%.*f,%.*f", coordDecimals, newX, coordDecimals, newY))
 	}
-	return line[start+1 : end]
+
+	return strings.Join(transformed, " ")
 }
 
-// transformPoints applies transform to a list of points
-func transformPoints(points string, t transform.Transform) string {
-	if points == "" {
+// transformAttr returns a transform attribute for transforms that cannot be
+// baked into an element's own coordinates (rotation or skew of a rect, ...)
+func transformAttr(m transform.Matrix) string {
+	if m.IsIdentity() {
 		return ""
 	}
+	return fmt.Sprintf(` transform="%s"`, m)
+}
 
-	coords := strings.Fields(strings.ReplaceAll(points, ",", " "))
-	var transformed []string
-
-	for i := 0; i < len(coords)-1; i += 2 {
-		x := parseF(coords[i])
-		y := parseF(coords[i+1])
+// scene holds the definitions that calls and style references resolve to
+type scene struct {
+	entities map[string]egf.Shape // H# entities
+	styles   map[string]*egf.Style
+}
 
-		newX, newY := t.ApplyToPoint(x, y)
-		transformed = append(transformed, fmt.Sprintf("%.2f,%.2f", newX, newY))
+// renderCall renders an entity instantiation using its transform, placed
+// within the parent transform m. The entity inherits the caller's style.
+func renderCall(call *egf.Call, m transform.Matrix, sc *scene, inherited *egf.Style) (string, error) {
+	entity, exists := sc.entities[call.ID]
+	if !exists {
+		pos := call.Pos()
+		return "", fmt.Errorf("line %d: CALL#%s references undefined entity", pos.Line, call.ID)
 	}
-
-	return strings.Join(transformed, " ")
+	return renderShape(entity, m.Multiply(call.Transform), sc, inherited)
 }
 
-// extractGroupContent extracts content from G[...] format
-func extractGroupContent(line string) string {
-	start := strings.Index(line, "[")
-	end := strings.LastIndex(line, "]")
-	if start == -1 || end == -1 || start >= end {
-		return "<!-- Empty group -->"
+// stroked reports whether a style paints a stroke, including the default
+// black stroke of an unstyled shape
+func stroked(s *egf.Style) bool {
+	if s == nil || s.Stroke == "" && s.Fill == "" {
+		return true
 	}
-	return line[start+1 : end]
+	return s.Stroke != "" && s.Stroke != "none"
 }
 
-// renderEntity renders an entity with transform
-func renderEntity(entity string, t transform.Transform) string {
-	return renderLine(entity, t)
+// scaleStroke returns a copy of s with its stroke width and dashes scaled
+// by k, so a stroke keeps its visible size when a scale is baked into the
+// shape's coordinates
+func scaleStroke(s *egf.Style, k float64) *egf.Style {
+	if math.Abs(k-1) < 1e-9 {
+		return s
+	}
+	scaled := egf.Style{Stroke: "black", Fill: "none"}
+	if s != nil {
+		scaled = *s
+	}
+	width := k
+	if scaled.Width != nil {
+		width *= *scaled.Width
+	}
+	scaled.Width = &width
+	if len(scaled.Dash) > 0 {
+		dash := make([]float64, len(scaled.Dash))
+		for i, v := range scaled.Dash {
+			dash[i] = v * k
+		}
+		scaled.Dash = dash
+	}
+	return &scaled
 }
 
-// renderLine renders a single EGF line as SVG
-func renderLine(line string, t transform.Transform) string {
-	switch {
-	case strings.HasPrefix(line, "R("):
-		p := extractParams(line)
-		if len(p) < 4 {
-			return fmt.Sprintf("<!-- Invalid rect: %s -->", line)
+// renderShape renders a single EGF shape as SVG. Transforms are baked into
+// coordinates where the element type allows it, drawn with baked, the style
+// with its stroke scaled to match; elements that keep a transform attribute
+// use the style as written, since the renderer scales their stroke. A
+// stroked shape under a non-uniform scale always keeps the attribute, as
+// no stroke width could match it. Named styles are expanded and a shape
+// without a style takes the one inherited from its groups.
+func renderShape(s egf.Shape, m transform.Matrix, sc *scene, inherited *egf.Style) (string, error) {
+	style, err := egf.ResolveStyle(egf.StyleOf(s), inherited, sc.styles)
+	if err != nil {
+		return "", fmt.Errorf("line %d: %w", s.Pos().Line, err)
+	}
+	baked := style
+	if _, group := s.(*egf.Group); !group && stroked(style) {
+		k, uniform := m.UniformScale()
+		if !uniform {
+			content, err := renderShape(s, transform.Identity(), sc, inherited)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSuffix(content, "/>") + transformAttr(m) + "/>", nil
+		}
+		baked = scaleStroke(style, math.Abs(k))
+	}
+	switch s := s.(type) {
+	case *egf.Rect:
+		if !m.IsAxisAligned() {
+			return fmt.Sprintf(`<rect x="%f" y="%f" width="%f" height="%f"%s %s/>`, s.X, s.Y, s.Width, s.Height, transformAttr(m), styleAttrs(style)), nil
+		}
+		x1, y1 := m.Apply(s.X, s.Y)
+		x2, y2 := m.Apply(s.X+s.Width, s.Y+s.Height)
+		x, y := math.Min(x1, x2), math.Min(y1, y2)
+		w, h := math.Abs(x2-x1), math.Abs(y2-y1)
+		return fmt.Sprintf(`<rect x="%f" y="%f" width="%f" height="%f" %s/>`, x, y, w, h, styleAttrs(baked)), nil
+
+	case *egf.Circle:
+		x, y := m.Apply(s.Cx, s.Cy)
+		if scale, ok := m.UniformScale(); ok {
+			r := s.R * math.Abs(scale)
+			return fmt.Sprintf(`<circle cx="%f" cy="%f" r="%f" %s/>`, x, y, r, styleAttrs(baked)), nil
+		}
+		if m.IsAxisAligned() {
+			rx, ry := s.R*math.Abs(m.A), s.R*math.Abs(m.D)
+			return fmt.Sprintf(`<ellipse cx="%f" cy="%f" rx="%f" ry="%f" %s/>`, x, y, rx, ry, styleAttrs(baked)), nil
 		}
-		x, y := t.ApplyToPoint(parseF(p[0]), parseF(p[1]))
-		w, h := parseF(p[2])*t.Scale, parseF(p[3])*t.Scale
-		style := extractStyle(line)
-		return fmt.Sprintf(`<rect x="%f" y="%f" width="%f" height="%f" %s/>`, x, y, w, h, style)
-
-	case strings.HasPrefix(line, "C("):
-		p := extractParams(line)
-		if len(p) < 3 {
-			return fmt.Sprintf("<!-- Invalid circle: %s -->", line)
+		return fmt.Sprintf(`<circle cx="%f" cy="%f" r="%f"%s %s/>`, s.Cx, s.Cy, s.R, transformAttr(m), styleAttrs(style)), nil
+
+	case *egf.Line:
+		x1, y1 := m.Apply(s.X1, s.Y1)
+		x2, y2 := m.Apply(s.X2, s.Y2)
+		return fmt.Sprintf(`<line x1="%f" y1="%f" x2="%f" y2="%f" %s/>`, x1, y1, x2, y2, styleAttrs(baked)), nil
+
+	case *egf.Path:
+		if m.IsIdentity() {
+			return fmt.Sprintf(`<path d="%s" %s/>`, s.Data, styleAttrs(baked)), nil
 		}
-		x, y := t.ApplyToPoint(parseF(p[0]), parseF(p[1]))
-		r := parseF(p[2]) * t.Scale
-		style := extractStyle(line)
-		return fmt.Sprintf(`<circle cx="%f" cy="%f" r="%f" %s/>`, x, y, r, style)
-
-	case strings.HasPrefix(line, "L("):
-		p := extractParams(line)
-		if len(p) < 4 {
-			return fmt.Sprintf("<!-- Invalid line: %s -->", line)
+		return fmt.Sprintf(`<path d="%s" %s/>`, s.Data.Transform(m).Format(coordDecimals), styleAttrs(baked)), nil
+
+	case *egf.Ellipse:
+		if !m.IsAxisAligned() {
+			return fmt.Sprintf(`<ellipse cx="%f" cy="%f" rx="%f" ry="%f"%s %s/>`, s.Cx, s.Cy, s.Rx, s.Ry, transformAttr(m), styleAttrs(style)), nil
 		}
-		x1, y1 := t.ApplyToPoint(parseF(p[0]), parseF(p[1]))
-		x2, y2 := t.ApplyToPoint(parseF(p[2]), parseF(p[3]))
-		style := extractStyle(line)
-		return fmt.Sprintf(`<line x1="%f" y1="%f" x2="%f" y2="%f" %s/>`, x1, y1, x2, y2, style)
-
-	case strings.HasPrefix(line, "P["):
-		path := extractPathData(line)
-		style := extractStyle(line)
-		// Path transform is skipped for now — would require parsing path commands
-		return fmt.Sprintf(`<path d="%s" %s/>`, path, style)
-
-	case strings.HasPrefix(line, "E("):
-		p := extractParams(line)
-		if len(p) < 4 {
-			return fmt.Sprintf("<!-- Invalid ellipse: %s -->", line)
+		cx, cy := m.Apply(s.Cx, s.Cy)
+		rx := s.Rx * math.Abs(m.A)
+		ry := s.Ry * math.Abs(m.D)
+		return fmt.Sprintf(`<ellipse cx="%f" cy="%f" rx="%f" ry="%f" %s/>`, cx, cy, rx, ry, styleAttrs(baked)), nil
+
+	case *egf.Polygon:
+		return fmt.Sprintf(`<polygon points="%s" %s/>`, transformPoints(s.Points, m), styleAttrs(baked)), nil
+
+	case *egf.Polyline:
+		return fmt.Sprintf(`<polyline points="%s" %s/>`, transformPoints(s.Points, m), styleAttrs(baked)), nil
+
+	case *egf.Group:
+		var b strings.Builder
+		b.WriteString("<g>\n")
+		for _, child := range s.Children {
+			var (
+				content string
+				err     error
+			)
+			switch child := child.(type) {
+			case *egf.Call:
+				content, err = renderCall(child, m, sc, style)
+			case egf.Shape:
+				content, err = renderShape(child, m, sc, style)
+			default:
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(content + "\n")
 		}
-		cx, cy := t.ApplyToPoint(parseF(p[0]), parseF(p[1]))
-		rx := parseF(p[2]) * t.Scale
-		ry := parseF(p[3]) * t.Scale
-		style := extractStyle(line)
-		return fmt.Sprintf(`<ellipse cx="%f" cy="%f" rx="%f" ry="%f" %s/>`, cx, cy, rx, ry, style)
-
-	case strings.HasPrefix(line, "PG["):
-		points := transformPoints(extractPointList(line), t)
-		style := extractStyle(line)
-		return fmt.Sprintf(`<polygon points="%s" %s/>`, points, style)
-
-	case strings.HasPrefix(line, "PL["):
-		points := transformPoints(extractPointList(line), t)
-		style := extractStyle(line)
-		return fmt.Sprintf(`<polyline points="%s" %s/>`, points, style)
+		b.WriteString("</g>")
+		return b.String(), nil
 
 	default:
-		return fmt.Sprintf("<!-- Unknown line: %s -->", line)
+		return fmt.Sprintf("<!-- Unknown shape: %T -->", s), nil
 	}
 }
@@ -0,0 +1,169 @@
+package egf
+
+import (
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/pathdata"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/transform"
+)
+
+// Pos is a line/column location in EGF source text (both 1-based)
+type Pos struct {
+	Line, Col int
+}
+
+// node carries the source position shared by every AST node
+type node struct {
+	pos Pos
+	// blankBefore records an empty line before the node in the source,
+	// which the formatter preserves (collapsed to a single blank line)
+	blankBefore bool
+}
+
+// Pos returns the position where the node starts in the source
+func (n *node) Pos() Pos { return n.pos }
+
+func (n *node) base() *node { return n }
+
+// Node is any statement of an EGF document
+type Node interface {
+	Pos() Pos
+	base() *node
+}
+
+// Shape is a drawable EGF primitive: R, C, L, E, P, PG, PL or G
+type Shape interface {
+	Node
+	isShape()
+}
+
+// Document is a parsed EGF file: an ordered list of top-level statements
+type Document struct {
+	Nodes []Node
+}
+
+// Canvas is the M(width,height,background) statement
+type Canvas struct {
+	node
+	Width, Height float64
+	Background    string
+}
+
+// EntityDef is the H#id = shape statement defining a reusable entity
+type EntityDef struct {
+	node
+	ID    string
+	Shape Shape
+}
+
+// StyleDef is the S#name = S(...) statement defining a named style that
+// shapes and groups refer to as S#name
+type StyleDef struct {
+	node
+	Name  string
+	Style *Style
+}
+
+// Call is the CALL#id T(...) statement instantiating an entity. The
+// transform may be written as T(...) or TM(...), see transform.FromArgs.
+type Call struct {
+	node
+	ID        string
+	Transform transform.Matrix
+}
+
+// Comment is a # comment. Trailing comments follow a statement on the same line.
+type Comment struct {
+	node
+	Text     string
+	Trailing bool
+}
+
+// Style is the S(stroke,fill,key=value...) suffix of a shape or group. An
+// empty Fill means it was omitted; nil or empty optional fields are unset,
+// leaving the SVG default in effect. A style written S#name has only Ref
+// set, naming a StyleDef.
+type Style struct {
+	Ref string
+
+	Stroke, Fill string
+
+	Width         *float64  // w: stroke width
+	StrokeOpacity *float64  // so: stroke opacity, 0 to 1
+	FillOpacity   *float64  // fo: fill opacity, 0 to 1
+	Dash          []float64 // dash: dash and gap lengths
+	Cap           string    // cap: butt, round or square
+	Join          string    // join: miter, round or bevel
+	MiterLimit    *float64  // miter: miter limit, at least 1
+	FillRule      string    // rule: nonzero or evenodd
+}
+
+// Point is a single x,y pair of a polygon or polyline
+type Point struct {
+	X, Y float64
+}
+
+// Rect is the R(x,y,width,height) shape
+type Rect struct {
+	node
+	X, Y, Width, Height float64
+	Style               *Style
+}
+
+// Circle is the C(cx,cy,r) shape
+type Circle struct {
+	node
+	Cx, Cy, R float64
+	Style     *Style
+}
+
+// Line is the L(x1,y1,x2,y2) shape
+type Line struct {
+	node
+	X1, Y1, X2, Y2 float64
+	Style          *Style
+}
+
+// Ellipse is the E(cx,cy,rx,ry) shape
+type Ellipse struct {
+	node
+	Cx, Cy, Rx, Ry float64
+	Style          *Style
+}
+
+// Path is the P[data] shape; Data is parsed SVG path syntax
+type Path struct {
+	node
+	Data  pathdata.Path
+	Style *Style
+}
+
+// Polygon is the PG[points] shape
+type Polygon struct {
+	node
+	Points []Point
+	Style  *Style
+}
+
+// Polyline is the PL[points] shape
+type Polyline struct {
+	node
+	Points []Point
+	Style  *Style
+}
+
+// Group is the G[...] shape containing nested shapes, calls and comments
+type Group struct {
+	node
+	Children []Node
+	// Style is inherited by the shapes and entities drawn within the group
+	// that have no style of their own
+	Style *Style
+}
+
+func (*Rect) isShape()     {}
+func (*Circle) isShape()   {}
+func (*Line) isShape()     {}
+func (*Ellipse) isShape()  {}
+func (*Path) isShape()     {}
+func (*Polygon) isShape()  {}
+func (*Polyline) isShape() {}
+func (*Group) isShape()    {}
@@ -0,0 +1,284 @@
+package egf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/color"
+)
+
+// Number tags stored in the low 3 bits of a number's leading varint.
+// Tags 0-5 are the count of decimal places of a scaled integer held in the
+// remaining bits (zigzag encoded), so 12.5 is stored as 125 with tag 1.
+const (
+	numMaxDecimals = 5
+	numFloat32     = 6
+	numFloat64     = 7
+)
+
+// maxExactInt is the largest integer magnitude a float64 holds exactly
+const maxExactInt = 1 << 53
+
+// Color tags
+const (
+	colorAbsent = iota
+	colorNone
+	colorShortHex // #rgb, 2 bytes
+	colorHex      // #rrggbb, 3 bytes
+	colorString
+	colorHexAlpha // #rgba or #rrggbbaa, 4 bytes
+)
+
+// binWriter accumulates the primitive encodings used by EGFB records
+type binWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *binWriter) byte(b byte) {
+	w.buf.WriteByte(b)
+}
+
+func (w *binWriter) uvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	w.buf.Write(tmp[:n])
+}
+
+func (w *binWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// number writes v in the smallest exact form: a scaled decimal varint when
+// its shortest decimal representation has few digits, else float32 when
+// lossless, else float64
+func (w *binWriter) number(v float64) {
+	if n, decimals, ok := scaledDecimal(v); ok {
+		w.uvarint(zigzag(n)<<3 | uint64(decimals))
+		return
+	}
+	if float64(float32(v)) == v {
+		w.byte(numFloat32)
+		binary.Write(&w.buf, binary.LittleEndian, math.Float32bits(float32(v)))
+		return
+	}
+	w.byte(numFloat64)
+	binary.Write(&w.buf, binary.LittleEndian, math.Float64bits(v))
+}
+
+// scaledDecimal splits v into n / 10^decimals when that is exact
+func scaledDecimal(v float64) (int64, int, bool) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, 0, false
+	}
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	decimals := 0
+	if dot := strings.IndexByte(s, '.'); dot != -1 {
+		decimals = len(s) - dot - 1
+		s = s[:dot] + s[dot+1:]
+	}
+	if decimals > numMaxDecimals {
+		return 0, 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n >= maxExactInt || n <= -maxExactInt {
+		return 0, 0, false
+	}
+	return n, decimals, true
+}
+
+// color writes a color, packing lowercase hex colors into bytes
+func (w *binWriter) color(c string) {
+	switch {
+	case c == "":
+		w.byte(colorAbsent)
+	case c == "none" || c == "#none":
+		w.byte(colorNone)
+	case isHexColor(c, 3):
+		w.byte(colorShortHex)
+		v, _ := strconv.ParseUint(c[1:], 16, 16)
+		w.byte(byte(v >> 8))
+		w.byte(byte(v))
+	case isHexColor(c, 6):
+		w.byte(colorHex)
+		v, _ := strconv.ParseUint(c[1:], 16, 32)
+		w.byte(byte(v >> 16))
+		w.byte(byte(v >> 8))
+		w.byte(byte(v))
+	case isHexColor(c, 4) || isHexColor(c, 8):
+		rgba, _ := color.Parse(c)
+		w.byte(colorHexAlpha)
+		w.byte(rgba.R)
+		w.byte(rgba.G)
+		w.byte(rgba.B)
+		w.byte(rgba.A)
+	default:
+		w.byte(colorString)
+		w.string(c)
+	}
+}
+
+// isHexColor reports whether c is "#" followed by n lowercase hex digits
+func isHexColor(c string, n int) bool {
+	if len(c) != n+1 || c[0] != '#' {
+		return false
+	}
+	for i := 1; i < len(c); i++ {
+		if !isDigit(c[i]) && (c[i] < 'a' || c[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func zigzag(n int64) uint64 {
+	return uint64((n << 1) ^ (n >> 63))
+}
+
+func unzigzag(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+// binReader decodes the primitives written by binWriter
+type binReader struct {
+	data []byte
+	off  int
+}
+
+func (r *binReader) errorf(kind error, format string, args ...interface{}) error {
+	return r.errorAt(r.off, kind, format, args...)
+}
+
+func (r *binReader) errorAt(off int, kind error, format string, args ...interface{}) error {
+	return &DecodeError{Offset: off, Kind: kind, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (r *binReader) byte() (byte, error) {
+	if r.off >= len(r.data) {
+		return 0, r.errorf(ErrTruncated, "unexpected end of data")
+	}
+	b := r.data[r.off]
+	r.off++
+	return b, nil
+}
+
+func (r *binReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.off+n > len(r.data) {
+		return nil, r.errorf(ErrTruncated, "unexpected end of data")
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+func (r *binReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.off:])
+	if n == 0 {
+		return 0, r.errorf(ErrTruncated, "unexpected end of data in varint")
+	}
+	if n < 0 {
+		return 0, r.errorf(ErrMalformed, "varint overflows 64 bits")
+	}
+	r.off += n
+	return v, nil
+}
+
+// count reads a varint element count, bounded by the bytes remaining so a
+// corrupt count cannot trigger a huge allocation
+func (r *binReader) count() (int, error) {
+	start := r.off
+	v, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if v > uint64(len(r.data)-r.off) {
+		return 0, r.errorAt(start, ErrBadLength, "length %d exceeds the %d bytes remaining", v, len(r.data)-r.off)
+	}
+	return int(v), nil
+}
+
+func (r *binReader) string() (string, error) {
+	n, err := r.count()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(n)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (r *binReader) number() (float64, error) {
+	head, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	switch tag := head & 7; tag {
+	case numFloat32:
+		b, err := r.bytes(4)
+		if err != nil {
+			return 0, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+	case numFloat64:
+		b, err := r.bytes(8)
+		if err != nil {
+			return 0, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	default:
+		return float64(unzigzag(head>>3)) / math.Pow10(int(tag)), nil
+	}
+}
+
+func (r *binReader) numbers(n int) ([]float64, error) {
+	out := make([]float64, n)
+	for i := range out {
+		v, err := r.number()
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (r *binReader) color() (string, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	switch tag {
+	case colorAbsent:
+		return "", nil
+	case colorNone:
+		return "none", nil
+	case colorShortHex:
+		b, err := r.bytes(2)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("#%03x", uint16(b[0])<<8|uint16(b[1])), nil
+	case colorHex:
+		b, err := r.bytes(3)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("#%02x%02x%02x", b[0], b[1], b[2]), nil
+	case colorString:
+		return r.string()
+	case colorHexAlpha:
+		b, err := r.bytes(4)
+		if err != nil {
+			return "", err
+		}
+		return color.Color{R: b[0], G: b[1], B: b[2], A: b[3]}.String(), nil
+	default:
+		return "", r.errorAt(r.off-1, ErrMalformed, "unknown color tag 0x%02x", tag)
+	}
+}
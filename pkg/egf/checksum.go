@@ -0,0 +1,95 @@
+package egf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// Files with FlagChecksum end in a trailer after the end mark. With a
+// chunk size it holds a CRC32 of every ChunkSize bytes of the record
+// stream followed by the chunk count as a uint32. The final four bytes are
+// always a CRC32 of everything before them. All values are little-endian.
+
+// appendChecksums appends the checksum trailer to data, which holds the
+// header and the complete record stream
+func appendChecksums(data []byte, h Header) []byte {
+	if h.ChunkSize > 0 {
+		stream := data[h.Size:]
+		count := 0
+		for off := 0; off < len(stream); off += h.ChunkSize {
+			data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk(stream, off, h.ChunkSize)))
+			count++
+		}
+		data = binary.LittleEndian.AppendUint32(data, uint32(count))
+	}
+	return binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+}
+
+// verifyChecksums checks the trailer of data and returns the offset where
+// the record stream ends. A mismatch still returns that offset along with
+// an ErrChecksum error, narrowed to the first bad chunk when possible.
+func verifyChecksums(data []byte, h Header) (int, error) {
+	end := len(data) - 4
+	if end < h.Size {
+		return 0, &DecodeError{Offset: len(data), Kind: ErrTruncated, Msg: "missing checksum trailer"}
+	}
+	var mismatch error
+	want := binary.LittleEndian.Uint32(data[end:])
+	if got := crc32.ChecksumIEEE(data[:end]); got != want {
+		mismatch = &DecodeError{Offset: end, Kind: ErrChecksum, Msg: fmt.Sprintf("file checksum %08x, expected %08x", got, want)}
+	}
+	if h.ChunkSize == 0 {
+		return end, mismatch
+	}
+
+	end -= 4
+	if end < h.Size {
+		return 0, &DecodeError{Offset: len(data), Kind: ErrTruncated, Msg: "missing chunk count"}
+	}
+	count := int(binary.LittleEndian.Uint32(data[end:]))
+	if count > (end-h.Size)/4 {
+		return 0, &DecodeError{Offset: end, Kind: ErrBadLength, Msg: fmt.Sprintf("chunk count %d exceeds the data", count)}
+	}
+	end -= 4 * count
+	sums := data[end : end+4*count]
+	stream := data[h.Size:end]
+	if expected := (len(stream) + h.ChunkSize - 1) / h.ChunkSize; count != expected {
+		return 0, &DecodeError{Offset: end + 4*count, Kind: ErrBadLength, Msg: fmt.Sprintf("chunk count %d, expected %d", count, expected)}
+	}
+	if mismatch == nil {
+		return end, nil
+	}
+	for i := 0; i < count; i++ {
+		off := i * h.ChunkSize
+		c := chunk(stream, off, h.ChunkSize)
+		want := binary.LittleEndian.Uint32(sums[4*i:])
+		if got := crc32.ChecksumIEEE(c); got != want {
+			return end, &DecodeError{
+				Offset: h.Size + off,
+				Kind:   ErrChecksum,
+				Msg:    fmt.Sprintf("chunk %d (%d bytes) checksum %08x, expected %08x", i, len(c), got, want),
+			}
+		}
+	}
+	return end, mismatch
+}
+
+// chunk returns the size bytes of stream starting at off, or fewer at the end
+func chunk(stream []byte, off, size int) []byte {
+	if off+size > len(stream) {
+		return stream[off:]
+	}
+	return stream[off : off+size]
+}
+
+// Verify fully decodes EGFB data, checking its checksums when present,
+// and returns its header
+func Verify(data []byte) (Header, error) {
+	h, err := ReadHeader(data)
+	if err != nil {
+		return h, err
+	}
+	_, err = Decode(data)
+	return h, err
+}
@@ -0,0 +1,60 @@
+package egf
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Files with FlagCompressed store everything after the header, end mark
+// included, as a single DEFLATE stream. Checksums cover the stored bytes.
+
+// compressRecords deflates a record stream at the given flate level
+func compressRecords(records []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(records); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Inflated records are capped at minInflateLimit plus maxInflateRatio
+// times the compressed size. Typed records rarely compress beyond 10:1, so
+// only a crafted stream reaches the cap, which keeps a small file from
+// exhausting memory.
+const (
+	minInflateLimit = 1 << 20
+	maxInflateRatio = 256
+)
+
+// decompressRecords inflates the compressed records found at off. When the
+// stream is damaged it returns the records recovered before the damage
+// along with the error.
+func decompressRecords(data []byte, off int) ([]byte, error) {
+	limit := int64(len(data))*maxInflateRatio + minInflateLimit
+	records, err := ioutil.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data)), limit+1))
+	if int64(len(records)) > limit {
+		return records[:limit], &DecodeError{Offset: off, Kind: ErrBadLength, Msg: fmt.Sprintf("compressed records inflate past %d bytes", limit)}
+	}
+	var corrupt flate.CorruptInputError
+	switch {
+	case err == nil:
+		return records, nil
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return records, &DecodeError{Offset: off + len(data), Kind: ErrTruncated, Msg: "compressed records cut short"}
+	case errors.As(err, &corrupt):
+		return records, &DecodeError{Offset: off + int(corrupt), Kind: ErrMalformed, Msg: "corrupt compressed data"}
+	default:
+		return records, &DecodeError{Offset: off, Kind: ErrMalformed, Msg: err.Error()}
+	}
+}
@@ -0,0 +1,144 @@
+package egf
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// encodeV1 builds a legacy version 1 file holding one record per line
+func encodeV1(lines ...string) []byte {
+	data := []byte(egfbMagic)
+	for _, line := range lines {
+		data = append(data, 0x01)
+		data = binary.LittleEndian.AppendUint16(data, uint16(len(line)))
+		data = append(data, line...)
+	}
+	return append(data, opEnd)
+}
+
+// FuzzDecode checks that no input makes the decoder panic, and that strict
+// and lenient mode agree on every input strict mode accepts
+func FuzzDecode(f *testing.F) {
+	f.Add(encodeV1("M(100,100)", "H#01 = R(10,10,50,50) S(#000,#f00)", "CALL#01 T(5,5,1,0)"))
+	doc, err := Parse(drawing(6) + "S#ink = S(#123,none,w=2,dash=(4,2))\nG[C(1,2,3) S#ink] S(#fff)\n")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, opts := range []EncodeOptions{
+		{},
+		{Quantize: true, Decimals: 1},
+		{Checksum: true},
+		{Checksum: true, ChunkSize: 64},
+		{Compress: true},
+		{Compress: true, Checksum: true, ChunkSize: 128},
+		{Frame: true},
+		{Frame: true, Compress: true, Checksum: true},
+	} {
+		data, err := EncodeWithOptions(doc, opts)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		strict, _, err := DecodeWithOptions(data, DecodeOptions{})
+		lenient, warnings, lerr := DecodeWithOptions(data, DecodeOptions{Lenient: true})
+		if err != nil {
+			return
+		}
+		if lerr != nil {
+			t.Fatalf("strict mode decoded the input but lenient mode failed: %v", lerr)
+		}
+		if len(warnings) != 0 {
+			t.Fatalf("lenient mode warned about input strict mode accepted: %v", warnings[0])
+		}
+		if s, l := Format(strict), Format(lenient); s != l {
+			t.Fatalf("strict and lenient mode disagree\nstrict:\n%s\nlenient:\n%s", s, l)
+		}
+	})
+}
+
+// recordOffsets returns the offset of the first byte of each record in a
+// framed, uncompressed file
+func recordOffsets(t *testing.T, data []byte) []int {
+	t.Helper()
+	h, err := ReadHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offsets []int
+	for off := h.Size; off < len(data); {
+		head, n := binary.Uvarint(data[off:])
+		off += n
+		if head&1 != 0 {
+			_, n = binary.Uvarint(data[off:])
+			off += n
+		}
+		offsets = append(offsets, off)
+		off += int(head >> 1)
+	}
+	return offsets
+}
+
+func TestDecodeLenientSkipsDamagedRecords(t *testing.T) {
+	doc := mustParse(t, "M(10,10)\nH#a = C(1,2,3)\nR(0,0,1,1)\nH#b = C(4,5,6)\nCALL#a T(1,1,1,0)\nCALL#b T(2,2,1,0)\n")
+	tests := []struct {
+		name string
+		// record is the index of the record whose opcode is corrupted
+		record   int
+		want     string
+		warnings int
+	}{
+		{"shape", 2, "M(10,10)\nH#a = C(1,2,3)\nH#b = C(4,5,6)\nCALL#a T(1,1,1,0)\nCALL#b T(2,2,1,0)\n", 1},
+		// the call to the skipped entity is skipped too, while the later
+		// entity keeps its index
+		{"entity", 1, "M(10,10)\nR(0,0,1,1)\nH#b = C(4,5,6)\nCALL#b T(2,2,1,0)\n", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeWithOptions(doc, EncodeOptions{Frame: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[recordOffsets(t, data)[tt.record]] = 0x7E
+
+			if _, err := Decode(data); !errors.Is(err, ErrUnknownOpcode) {
+				t.Errorf("strict decode error = %v, want %v", err, ErrUnknownOpcode)
+			}
+			got, warnings, err := DecodeWithOptions(data, DecodeOptions{Lenient: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("got %d warnings, want %d: %v", len(warnings), tt.warnings, warnings)
+			}
+			if s := Format(got); s != tt.want {
+				t.Errorf("lenient decode kept\n%s\nwant\n%s", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeepNesting(t *testing.T) {
+	g := &Group{}
+	doc := &Document{Nodes: []Node{g}}
+	for i := 0; i < maxGroupDepth; i++ {
+		child := &Group{}
+		g.Children = []Node{child}
+		g = child
+	}
+	if _, err := Encode(doc); err == nil {
+		t.Error("encoded groups nested deeper than the decoder accepts")
+	}
+
+	data := Header{Version: 2}.bytes()
+	for i := 0; i <= maxGroupDepth; i++ {
+		data = append(data, opGroup, 1)
+	}
+	data = append(data, opCircle, 0, 0, 0, 0, opEnd)
+	if _, err := Decode(data); !errors.Is(err, ErrTooDeep) {
+		t.Errorf("decode error = %v, want %v", err, ErrTooDeep)
+	}
+}
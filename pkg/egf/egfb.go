@@ -0,0 +1,1060 @@
+package egf
+
+import (
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/pathdata"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/transform"
+)
+
+// Record opcodes. Shape opcodes match the version 1 line opcodes.
+const (
+	opCanvas      = 0x01
+	opRect        = 0x02
+	opCircle      = 0x03
+	opLine        = 0x04
+	opPath        = 0x05
+	opEllipse     = 0x06
+	opPolygon     = 0x07
+	opPolyline    = 0x08
+	opGroup       = 0x09
+	opStyledGroup = 0x0A // a group followed by its inherited style
+	opEntityDef   = 0x10
+	opCall        = 0x11
+	opComment     = 0x12
+	opBlank       = 0x13
+	opStyleDef    = 0x14
+	opEnd         = 0xFF
+)
+
+// maxGroupDepth is the deepest group nesting Encode writes and Decode
+// accepts, bounding the decoder's recursion
+const maxGroupDepth = 256
+
+// Style field bits. The mask is a varint, so files using only stroke and
+// fill keep the single byte of earlier encoders.
+const (
+	styleStroke = 1 << iota
+	styleFill
+	styleWidth
+	styleStrokeOpacity
+	styleFillOpacity
+	styleDash
+	styleCap
+	styleJoin
+	styleMiterLimit
+	styleFillRule
+	// styleRef replaces the other fields with the name of a StyleDef
+	styleRef
+
+	styleFields = styleRef<<1 - 1
+)
+
+// Transform field bits
+const (
+	transformTranslate = 1 << iota
+	transformLinear
+)
+
+// EncodeOptions controls how Encode writes EGFB data. The zero value
+// writes exact, uncompressed numbers without checksums.
+type EncodeOptions struct {
+	// Quantize rounds every number to Decimals decimal places before
+	// encoding. This is lossy but keeps coordinates in short varints.
+	Quantize bool
+	Decimals int
+	// Checksum appends a CRC32 of the file. A positive ChunkSize also adds
+	// one checksum per ChunkSize bytes of records to locate corruption.
+	Checksum  bool
+	ChunkSize int
+	// Compress deflates the record stream at CompressionLevel, a
+	// compress/flate level from 1 to 9; zero selects the default level.
+	Compress         bool
+	CompressionLevel int
+	// Frame prefixes each top-level record with its length, so lenient
+	// decoding can skip a damaged record instead of stopping at it
+	Frame bool
+}
+
+// Encode serializes a document as EGFB with exact numbers. Numbers are
+// stored as scaled decimal varints or raw floats, hex colors are packed
+// into bytes and entity IDs are written once and then referenced by index.
+func Encode(doc *Document) ([]byte, error) {
+	return EncodeWithOptions(doc, EncodeOptions{})
+}
+
+// EncodeWithOptions serializes a document as EGFB, in the oldest version
+// that supports every feature it uses
+func EncodeWithOptions(doc *Document, opts EncodeOptions) ([]byte, error) {
+	h := Header{Version: 2}
+	if opts.Quantize {
+		if opts.Decimals < 0 || opts.Decimals > numMaxDecimals {
+			return nil, fmt.Errorf("quantize decimals must be between 0 and %d, got %d", numMaxDecimals, opts.Decimals)
+		}
+		h.Flags |= FlagPrecision
+		h.Decimals = opts.Decimals
+	}
+	if opts.Checksum {
+		if opts.ChunkSize < 0 {
+			return nil, fmt.Errorf("checksum chunk size must not be negative, got %d", opts.ChunkSize)
+		}
+		h.Flags |= FlagChecksum
+		h.ChunkSize = opts.ChunkSize
+	}
+	level := opts.CompressionLevel
+	if opts.Compress {
+		if level == 0 {
+			level = flate.DefaultCompression
+		} else if level < flate.BestSpeed || level > flate.BestCompression {
+			return nil, fmt.Errorf("compression level must be between %d and %d, got %d", flate.BestSpeed, flate.BestCompression, level)
+		}
+		h.Flags |= FlagCompressed
+	}
+	if opts.Frame {
+		h.Flags |= FlagFramed
+	}
+	h.Size = len(h.bytes())
+
+	e := &encoder{ids: map[string]uint64{}, decimals: -1, version: h.Version}
+	if opts.Quantize {
+		e.decimals = opts.Decimals
+	}
+	e.buf.Write(h.bytes())
+	if opts.Frame {
+		e.frames(doc.Nodes)
+	} else {
+		e.nodes(doc.Nodes)
+		e.byte(opEnd)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	data := e.buf.Bytes()
+	h.Version = e.version
+	data[len(egfbMagic)+1] = byte(h.Version)
+	if opts.Compress {
+		records, err := compressRecords(data[h.Size:], level)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data[:h.Size], records...)
+	}
+	if opts.Checksum {
+		data = appendChecksums(data, h)
+	}
+	return data, nil
+}
+
+// DecodeOptions controls how corrupt EGFB data is handled
+type DecodeOptions struct {
+	// Lenient keeps the records decoded before the first error and reports
+	// the error as a warning instead of failing. In framed files a damaged
+	// record is skipped and decoding goes on. Header errors still fail.
+	Lenient bool
+}
+
+// Decode parses EGFB data of any supported version into a document,
+// failing on the first sign of corruption
+func Decode(data []byte) (*Document, error) {
+	doc, _, err := DecodeWithOptions(data, DecodeOptions{})
+	return doc, err
+}
+
+// DecodeWithOptions parses EGFB data of any supported version, verifying
+// its checksums and decompressing its records when flagged. Version 1 files are decoded line by line
+// through the EGF text parser. In lenient mode the returned warnings
+// describe checksum mismatches and the data that was dropped.
+func DecodeWithOptions(data []byte, opts DecodeOptions) (*Document, []*DecodeError, error) {
+	h, err := ReadHeader(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if h.Version == 1 {
+		text, warnings, err := decodeV1(data, h.Size, opts.Lenient)
+		if err != nil {
+			return nil, nil, err
+		}
+		doc, err := Parse(text)
+		if err != nil {
+			return nil, nil, err
+		}
+		return doc, warnings, nil
+	}
+
+	end := len(data)
+	var warnings []*DecodeError
+	// warn records err as a warning in lenient mode, else returns it
+	warn := func(err error) error {
+		derr, ok := err.(*DecodeError)
+		if !opts.Lenient || !ok {
+			return err
+		}
+		warnings = append(warnings, derr)
+		return nil
+	}
+
+	if h.Flags&FlagChecksum != 0 {
+		end, err = verifyChecksums(data, h)
+		if err != nil && !errors.Is(err, ErrChecksum) {
+			return nil, nil, err
+		}
+		if err != nil {
+			if err := warn(err); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if h.Flags&FlagCompressed != 0 {
+		records, err := decompressRecords(data[h.Size:end], h.Size)
+		if err != nil {
+			if err := warn(err); err != nil {
+				return nil, nil, err
+			}
+		}
+		// Decode errors in compressed files give offsets into this
+		// decompressed copy
+		data = append(data[:h.Size:h.Size], records...)
+		end = len(data)
+	}
+
+	d := &decoder{binReader: binReader{data: data[:end], off: h.Size}, version: h.Version, framed: h.Flags&FlagFramed != 0}
+	doc := &Document{}
+	for {
+		n, skipped, err := d.record()
+		if err != nil {
+			if err := warn(err); err != nil {
+				return nil, nil, err
+			}
+			if skipped {
+				continue
+			}
+			return doc, warnings, nil
+		}
+		if n == nil {
+			return doc, warnings, nil
+		}
+		doc.Nodes = append(doc.Nodes, n)
+	}
+}
+
+// ReadEGFB reads and decodes EGFB data from r, returning any warnings
+// produced in lenient mode
+func ReadEGFB(r io.Reader, opts DecodeOptions) (*Document, []*DecodeError, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return DecodeWithOptions(data, opts)
+}
+
+// WriteEGFB encodes a document as EGFB and writes it to w
+func WriteEGFB(w io.Writer, doc *Document, opts EncodeOptions) error {
+	data, err := EncodeWithOptions(doc, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// EncodeToEGFB encodes EGF text to binary EGFB format
+func EncodeToEGFB(egfContent string, egfbFile string) error {
+	return EncodeToEGFBWithOptions(egfContent, egfbFile, EncodeOptions{})
+}
+
+// EncodeToEGFBWithOptions encodes EGF text to an EGFB file with the given
+// precision, checksum and compression options
+func EncodeToEGFBWithOptions(egfContent string, egfbFile string, opts EncodeOptions) error {
+	doc, err := Parse(egfContent)
+	if err != nil {
+		return err
+	}
+	data, err := EncodeWithOptions(doc, opts)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(egfbFile, data, 0644)
+}
+
+// DecodeFromEGFB decodes binary EGFB format back to EGF text
+func DecodeFromEGFB(egfbFile string) (string, error) {
+	text, _, err := DecodeFromEGFBWithOptions(egfbFile, DecodeOptions{})
+	return text, err
+}
+
+// DecodeFromEGFBWithOptions decodes an EGFB file back to EGF text,
+// returning any warnings produced in lenient mode
+func DecodeFromEGFBWithOptions(egfbFile string, opts DecodeOptions) (string, []*DecodeError, error) {
+	f, err := os.Open(egfbFile)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	doc, warnings, err := ReadEGFB(f, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	return Format(doc), warnings, nil
+}
+
+// encoder writes typed EGFB records
+type encoder struct {
+	binWriter
+	// ids maps entity IDs to their index in the order first written
+	ids map[string]uint64
+	// decimals is the quantization precision, or -1 for exact numbers
+	decimals int
+	// err is the first style or group that cannot be encoded
+	err error
+	// version is the oldest EGFB version able to hold the records
+	// written so far
+	version int
+	// depth is the group nesting of the record being written
+	depth int
+}
+
+// need raises the version written to at least v
+func (e *encoder) need(v int) {
+	if v > e.version {
+		e.version = v
+	}
+}
+
+// color writes a color, noting that alpha needs version 3
+func (e *encoder) color(c string) {
+	if isHexColor(c, 4) || isHexColor(c, 8) {
+		e.need(3)
+	}
+	e.binWriter.color(c)
+}
+
+// number writes v, rounded first when quantizing
+func (e *encoder) number(v float64) {
+	if e.decimals >= 0 {
+		scale := math.Pow10(e.decimals)
+		v = math.Round(v*scale) / scale
+	}
+	e.binWriter.number(v)
+}
+
+// frames writes each top-level node, then the end mark, as a frame: a
+// uvarint of the record length shifted left once, with the low bit set when
+// a uvarint count of the entity IDs the record introduces follows, then the
+// record itself. The counts let a decoder that skips a record keep the
+// entity indices of later references aligned.
+func (e *encoder) frames(nodes []Node) {
+	for _, n := range nodes {
+		start, ids := e.buf.Len(), len(e.ids)
+		e.nodes([]Node{n})
+		record := append([]byte(nil), e.buf.Bytes()[start:]...)
+		e.buf.Truncate(start)
+		e.frame(record, len(e.ids)-ids)
+	}
+	e.frame([]byte{opEnd}, 0)
+}
+
+func (e *encoder) frame(record []byte, introduced int) {
+	if introduced == 0 {
+		e.uvarint(uint64(len(record)) << 1)
+	} else {
+		e.uvarint(uint64(len(record))<<1 | 1)
+		e.uvarint(uint64(introduced))
+	}
+	e.buf.Write(record)
+}
+
+func (e *encoder) nodes(nodes []Node) {
+	for _, n := range nodes {
+		if n.base().blankBefore {
+			e.byte(opBlank)
+		}
+		e.node(n)
+	}
+}
+
+func (e *encoder) node(n Node) {
+	switch n := n.(type) {
+	case *Canvas:
+		e.byte(opCanvas)
+		e.numbers(n.Width, n.Height)
+		e.color(n.Background)
+	case *EntityDef:
+		e.byte(opEntityDef)
+		e.entityRef(n.ID)
+		e.node(n.Shape)
+	case *StyleDef:
+		e.need(3)
+		e.byte(opStyleDef)
+		e.string(n.Name)
+		e.style(n.Style)
+	case *Call:
+		e.byte(opCall)
+		e.entityRef(n.ID)
+		e.transform(n.Transform)
+	case *Comment:
+		e.byte(opComment)
+		if n.Trailing {
+			e.byte(1)
+		} else {
+			e.byte(0)
+		}
+		e.string(n.Text)
+	case *Rect:
+		e.byte(opRect)
+		e.numbers(n.X, n.Y, n.Width, n.Height)
+		e.style(n.Style)
+	case *Circle:
+		e.byte(opCircle)
+		e.numbers(n.Cx, n.Cy, n.R)
+		e.style(n.Style)
+	case *Line:
+		e.byte(opLine)
+		e.numbers(n.X1, n.Y1, n.X2, n.Y2)
+		e.style(n.Style)
+	case *Ellipse:
+		e.byte(opEllipse)
+		e.numbers(n.Cx, n.Cy, n.Rx, n.Ry)
+		e.style(n.Style)
+	case *Path:
+		e.byte(opPath)
+		e.uvarint(uint64(len(n.Data)))
+		for _, seg := range n.Data {
+			e.byte(seg.Cmd)
+			e.numbers(seg.Args...)
+		}
+		e.style(n.Style)
+	case *Polygon:
+		e.byte(opPolygon)
+		e.points(n.Points)
+		e.style(n.Style)
+	case *Polyline:
+		e.byte(opPolyline)
+		e.points(n.Points)
+		e.style(n.Style)
+	case *Group:
+		e.depth++
+		defer func() { e.depth-- }()
+		if e.depth > maxGroupDepth && e.err == nil {
+			e.err = fmt.Errorf("groups nested more than %d deep", maxGroupDepth)
+		}
+		if n.Style != nil {
+			e.need(3)
+			e.byte(opStyledGroup)
+		} else {
+			e.byte(opGroup)
+		}
+		e.uvarint(uint64(len(n.Children)))
+		e.nodes(n.Children)
+		if n.Style != nil {
+			e.style(n.Style)
+		}
+	}
+}
+
+// entityRef writes index+1 for known IDs, or 0 followed by the ID string
+// the first time an ID appears
+func (e *encoder) entityRef(id string) {
+	if idx, ok := e.ids[id]; ok {
+		e.uvarint(idx + 1)
+		return
+	}
+	e.ids[id] = uint64(len(e.ids))
+	e.uvarint(0)
+	e.string(id)
+}
+
+func (e *encoder) numbers(values ...float64) {
+	for _, v := range values {
+		e.number(v)
+	}
+}
+
+func (e *encoder) points(points []Point) {
+	e.uvarint(uint64(len(points)))
+	for _, p := range points {
+		e.numbers(p.X, p.Y)
+	}
+}
+
+// style writes the field mask and the fields present. Style numbers are
+// written exactly, since quantizing an opacity or a thin stroke would
+// change it far more than the coordinates.
+func (e *encoder) style(s *Style) {
+	if s == nil {
+		e.byte(0)
+		return
+	}
+	if err := s.Validate(); err != nil && e.err == nil {
+		e.err = fmt.Errorf("invalid style: %w", err)
+	}
+	if s.Ref != "" {
+		e.need(3)
+		e.uvarint(styleRef)
+		e.string(s.Ref)
+		return
+	}
+	var mask uint64
+	for _, f := range []struct {
+		bit uint64
+		set bool
+	}{
+		{styleStroke, s.Stroke != ""},
+		{styleFill, s.Fill != ""},
+		{styleWidth, s.Width != nil},
+		{styleStrokeOpacity, s.StrokeOpacity != nil},
+		{styleFillOpacity, s.FillOpacity != nil},
+		{styleDash, len(s.Dash) > 0},
+		{styleCap, s.Cap != ""},
+		{styleJoin, s.Join != ""},
+		{styleMiterLimit, s.MiterLimit != nil},
+		{styleFillRule, s.FillRule != ""},
+	} {
+		if f.set {
+			mask |= f.bit
+		}
+	}
+	if mask&^(styleStroke|styleFill) != 0 {
+		e.need(3)
+	}
+	e.uvarint(mask)
+	if mask&styleStroke != 0 {
+		e.color(s.Stroke)
+	}
+	if mask&styleFill != 0 {
+		e.color(s.Fill)
+	}
+	for _, v := range []*float64{s.Width, s.StrokeOpacity, s.FillOpacity} {
+		if v != nil {
+			e.binWriter.number(*v)
+		}
+	}
+	if mask&styleDash != 0 {
+		e.uvarint(uint64(len(s.Dash)))
+		for _, v := range s.Dash {
+			e.binWriter.number(v)
+		}
+	}
+	if mask&styleCap != 0 {
+		e.byte(byte(enumIndex(lineCaps, s.Cap)))
+	}
+	if mask&styleJoin != 0 {
+		e.byte(byte(enumIndex(lineJoins, s.Join)))
+	}
+	if mask&styleMiterLimit != 0 {
+		e.binWriter.number(*s.MiterLimit)
+	}
+	if mask&styleFillRule != 0 {
+		e.byte(byte(enumIndex(fillRules, s.FillRule)))
+	}
+}
+
+// transform writes only the non-identity parts of a matrix. The linear part
+// is written exactly: rounding it to the encoder's precision would distort
+// rotations and scales, whose error grows with the coordinates.
+func (e *encoder) transform(m transform.Matrix) {
+	var mask byte
+	if m.E != 0 || m.F != 0 {
+		mask |= transformTranslate
+	}
+	if m.A != 1 || m.B != 0 || m.C != 0 || m.D != 1 {
+		mask |= transformLinear
+	}
+	e.byte(mask)
+	if mask&transformLinear != 0 {
+		for _, v := range []float64{m.A, m.B, m.C, m.D} {
+			e.binWriter.number(v)
+		}
+	}
+	if mask&transformTranslate != 0 {
+		e.numbers(m.E, m.F)
+	}
+}
+
+// decoder reads typed EGFB records back into AST nodes
+type decoder struct {
+	binReader
+	// ids are the entity IDs in the order introduced. IDs introduced by a
+	// skipped record are reserved as "" so later indices stay aligned.
+	ids []string
+	// version is the file's EGFB version
+	version int
+	// framed is set when top-level records are length-prefixed
+	framed bool
+	// depth is the group nesting of the record being read
+	depth int
+}
+
+// require fails unless the file's version has the feature at off
+func (d *decoder) require(v int, off int, feature string) error {
+	if d.version < v {
+		return d.errorAt(off, ErrMalformed, "%s needs EGFB version %d, file is version %d", feature, v, d.version)
+	}
+	return nil
+}
+
+// color reads a color, rejecting alpha before version 3
+func (d *decoder) color() (string, error) {
+	if d.off < len(d.data) && d.data[d.off] == colorHexAlpha {
+		if err := d.require(3, d.off, "a color with alpha"); err != nil {
+			return "", err
+		}
+	}
+	return d.binReader.color()
+}
+
+// record decodes the next top-level record, returning nil at the end mark.
+// skipped reports that err was confined to a framed record, which was
+// skipped so decoding can go on with the next one.
+func (d *decoder) record() (n Node, skipped bool, err error) {
+	if d.off >= len(d.data) {
+		return nil, false, d.errorf(ErrMissingTerminator, "data ends without end marker 0x%02x", opEnd)
+	}
+	if d.framed {
+		return d.frame()
+	}
+	op, err := d.byte()
+	if err != nil || op == opEnd {
+		return nil, false, err
+	}
+	n, err = d.node(op, true)
+	return n, false, err
+}
+
+// frame decodes a framed record, as written by encoder.frames. The frame
+// header is trusted only as far as the data goes: a record running past the
+// end of the data is fatal, while any error inside the record skips it.
+func (d *decoder) frame() (Node, bool, error) {
+	start := d.off
+	head, err := d.uvarint()
+	if err != nil {
+		return nil, false, err
+	}
+	var introduced uint64
+	if head&1 != 0 {
+		if introduced, err = d.uvarint(); err != nil {
+			return nil, false, err
+		}
+	}
+	length, remaining := head>>1, uint64(len(d.data)-d.off)
+	if length == 0 || length > remaining {
+		return nil, false, d.errorAt(start, ErrBadLength, "record length %d with %d bytes remaining", length, remaining)
+	}
+	// Every introduced ID takes at least two bytes of the record
+	if introduced > length/2 {
+		return nil, false, d.errorAt(start, ErrMalformed, "record of %d bytes cannot introduce %d entities", length, introduced)
+	}
+	end := d.off + int(length)
+	if d.data[d.off] == opEnd {
+		if length != 1 {
+			return nil, false, d.errorAt(start, ErrBadLength, "end marker record of %d bytes", length)
+		}
+		d.off = end
+		return nil, false, nil
+	}
+
+	ids := len(d.ids)
+	data := d.data
+	d.data = data[:end]
+	op := d.data[d.off]
+	d.off++
+	n, err := d.node(op, true)
+	d.data = data
+	if err == nil && d.off != end {
+		err = d.errorAt(d.off, ErrBadLength, "record ends %d bytes before its frame", end-d.off)
+	}
+	if err == nil && len(d.ids)-ids != int(introduced) {
+		err = d.errorAt(start, ErrMalformed, "record introduces %d entities, its frame %d", len(d.ids)-ids, introduced)
+	}
+	if err != nil {
+		d.off = end
+		d.ids = d.ids[:ids]
+		for i := uint64(0); i < introduced; i++ {
+			d.ids = append(d.ids, "")
+		}
+		return nil, true, err
+	}
+	return n, false, nil
+}
+
+// node decodes the record for op. Statements that only make sense at the
+// top level (canvas, entity definitions) are rejected inside groups.
+func (d *decoder) node(op byte, topLevel bool) (Node, error) {
+	start := d.off
+	switch op {
+	case opBlank:
+		next, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		if next == opBlank || next == opEnd {
+			return nil, d.errorAt(d.off-1, ErrMalformed, "blank line marker must precede a record")
+		}
+		n, err := d.node(next, topLevel)
+		if err != nil {
+			return nil, err
+		}
+		n.base().blankBefore = true
+		return n, nil
+	case opCanvas:
+		if !topLevel {
+			break
+		}
+		v, err := d.numbers(2)
+		if err != nil {
+			return nil, err
+		}
+		bg, err := d.color()
+		if err != nil {
+			return nil, err
+		}
+		return &Canvas{Width: v[0], Height: v[1], Background: bg}, nil
+	case opEntityDef:
+		if !topLevel {
+			break
+		}
+		id, err := d.entityRef()
+		if err != nil {
+			return nil, err
+		}
+		op, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		n, err := d.node(op, false)
+		if err != nil {
+			return nil, err
+		}
+		shape, ok := n.(Shape)
+		if !ok {
+			return nil, d.errorf(ErrMalformed, "entity H#%s must define a shape", id)
+		}
+		return &EntityDef{ID: id, Shape: shape}, nil
+	case opCall:
+		id, err := d.entityRef()
+		if err != nil {
+			return nil, err
+		}
+		m, err := d.transform()
+		if err != nil {
+			return nil, err
+		}
+		return &Call{ID: id, Transform: m}, nil
+	case opComment:
+		trailing, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		text, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		return &Comment{Text: text, Trailing: trailing != 0}, nil
+	case opStyleDef:
+		if !topLevel {
+			break
+		}
+		if err := d.require(3, start-1, "a named style"); err != nil {
+			return nil, err
+		}
+		name, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		style, err := d.style()
+		if err != nil {
+			return nil, err
+		}
+		if style == nil || style.Ref != "" {
+			return nil, d.errorAt(start, ErrMalformed, "style S#%s must define its fields", name)
+		}
+		return &StyleDef{Name: name, Style: style}, nil
+	case opGroup, opStyledGroup:
+		if op == opStyledGroup {
+			if err := d.require(3, start-1, "a styled group"); err != nil {
+				return nil, err
+			}
+		}
+		d.depth++
+		defer func() { d.depth-- }()
+		if d.depth > maxGroupDepth {
+			return nil, d.errorAt(start-1, ErrTooDeep, "groups nested more than %d deep", maxGroupDepth)
+		}
+		count, err := d.count()
+		if err != nil {
+			return nil, err
+		}
+		g := &Group{}
+		for i := 0; i < count; i++ {
+			op, err := d.byte()
+			if err != nil {
+				return nil, err
+			}
+			child, err := d.node(op, false)
+			if err != nil {
+				return nil, err
+			}
+			g.Children = append(g.Children, child)
+		}
+		if op == opStyledGroup {
+			if g.Style, err = d.style(); err != nil {
+				return nil, err
+			}
+		}
+		return g, nil
+	default:
+		return d.shape(op)
+	}
+	return nil, d.errorAt(start-1, ErrMalformed, "opcode 0x%02x not allowed inside an entity or group", op)
+}
+
+// shape decodes the geometric primitives, which all end with a style
+func (d *decoder) shape(op byte) (Shape, error) {
+	var (
+		s   Shape
+		err error
+	)
+	switch op {
+	case opRect, opLine, opEllipse:
+		var v []float64
+		if v, err = d.numbers(4); err != nil {
+			return nil, err
+		}
+		switch op {
+		case opRect:
+			s = &Rect{X: v[0], Y: v[1], Width: v[2], Height: v[3]}
+		case opLine:
+			s = &Line{X1: v[0], Y1: v[1], X2: v[2], Y2: v[3]}
+		default:
+			s = &Ellipse{Cx: v[0], Cy: v[1], Rx: v[2], Ry: v[3]}
+		}
+	case opCircle:
+		var v []float64
+		if v, err = d.numbers(3); err != nil {
+			return nil, err
+		}
+		s = &Circle{Cx: v[0], Cy: v[1], R: v[2]}
+	case opPath:
+		var data pathdata.Path
+		if data, err = d.path(); err != nil {
+			return nil, err
+		}
+		s = &Path{Data: data}
+	case opPolygon, opPolyline:
+		var points []Point
+		if points, err = d.points(); err != nil {
+			return nil, err
+		}
+		if op == opPolygon {
+			s = &Polygon{Points: points}
+		} else {
+			s = &Polyline{Points: points}
+		}
+	default:
+		return nil, d.errorAt(d.off-1, ErrUnknownOpcode, "0x%02x", op)
+	}
+
+	style, err := d.style()
+	if err != nil {
+		return nil, err
+	}
+	if style != nil {
+		SetStyle(s, style)
+	}
+	return s, nil
+}
+
+func (d *decoder) entityRef() (string, error) {
+	ref, err := d.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if ref == 0 {
+		id, err := d.string()
+		if err != nil {
+			return "", err
+		}
+		d.ids = append(d.ids, id)
+		return id, nil
+	}
+	if ref > uint64(len(d.ids)) {
+		return "", d.errorf(ErrMalformed, "entity reference %d out of range", ref)
+	}
+	if d.ids[ref-1] == "" {
+		return "", d.errorf(ErrMalformed, "entity reference %d names an entity in a skipped record", ref)
+	}
+	return d.ids[ref-1], nil
+}
+
+func (d *decoder) path() (pathdata.Path, error) {
+	count, err := d.count()
+	if err != nil {
+		return nil, err
+	}
+	path := make(pathdata.Path, 0, count)
+	for i := 0; i < count; i++ {
+		cmd, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		n := pathdata.Arity(cmd)
+		if n < 0 {
+			return nil, d.errorAt(d.off-1, ErrMalformed, "invalid path command %q", cmd)
+		}
+		var args []float64
+		if n > 0 {
+			if args, err = d.numbers(n); err != nil {
+				return nil, err
+			}
+		}
+		path = append(path, pathdata.Segment{Cmd: cmd, Args: args})
+	}
+	return path, nil
+}
+
+func (d *decoder) points() ([]Point, error) {
+	count, err := d.count()
+	if err != nil {
+		return nil, err
+	}
+	points := make([]Point, count)
+	for i := range points {
+		v, err := d.numbers(2)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = Point{X: v[0], Y: v[1]}
+	}
+	return points, nil
+}
+
+func (d *decoder) style() (*Style, error) {
+	start := d.off
+	mask, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if mask == 0 {
+		return nil, nil
+	}
+	if mask&^styleFields != 0 {
+		return nil, d.errorAt(start, ErrMalformed, "unknown style fields 0x%02x", mask)
+	}
+	if mask&^(styleStroke|styleFill) != 0 {
+		if err := d.require(3, start, "a style beyond stroke and fill"); err != nil {
+			return nil, err
+		}
+	}
+	if mask&styleRef != 0 {
+		if mask != styleRef {
+			return nil, d.errorAt(start, ErrMalformed, "style reference combined with other fields 0x%02x", mask)
+		}
+		ref, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		return &Style{Ref: ref}, nil
+	}
+	s := &Style{}
+	if mask&styleStroke != 0 {
+		if s.Stroke, err = d.color(); err != nil {
+			return nil, err
+		}
+	}
+	if mask&styleFill != 0 {
+		if s.Fill, err = d.color(); err != nil {
+			return nil, err
+		}
+	}
+	number := func(bit uint64, field **float64) error {
+		if mask&bit == 0 {
+			return nil
+		}
+		v, err := d.number()
+		if err != nil {
+			return err
+		}
+		*field = &v
+		return nil
+	}
+	enum := func(bit uint64, field *string, values []string) error {
+		if mask&bit == 0 {
+			return nil
+		}
+		i, err := d.byte()
+		if err != nil {
+			return err
+		}
+		if int(i) >= len(values) {
+			return d.errorAt(d.off-1, ErrMalformed, "unknown style value %d", i)
+		}
+		*field = values[i]
+		return nil
+	}
+	if err := number(styleWidth, &s.Width); err != nil {
+		return nil, err
+	}
+	if err := number(styleStrokeOpacity, &s.StrokeOpacity); err != nil {
+		return nil, err
+	}
+	if err := number(styleFillOpacity, &s.FillOpacity); err != nil {
+		return nil, err
+	}
+	if mask&styleDash != 0 {
+		n, err := d.count()
+		if err != nil {
+			return nil, err
+		}
+		if s.Dash, err = d.numbers(n); err != nil {
+			return nil, err
+		}
+	}
+	if err := enum(styleCap, &s.Cap, lineCaps); err != nil {
+		return nil, err
+	}
+	if err := enum(styleJoin, &s.Join, lineJoins); err != nil {
+		return nil, err
+	}
+	if err := number(styleMiterLimit, &s.MiterLimit); err != nil {
+		return nil, err
+	}
+	if err := enum(styleFillRule, &s.FillRule, fillRules); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (d *decoder) transform() (transform.Matrix, error) {
+	m := transform.Identity()
+	mask, err := d.byte()
+	if err != nil {
+		return m, err
+	}
+	if mask&^(transformTranslate|transformLinear) != 0 {
+		return m, d.errorAt(d.off-1, ErrMalformed, "unknown transform fields 0x%02x", mask)
+	}
+	if mask&transformLinear != 0 {
+		v, err := d.numbers(4)
+		if err != nil {
+			return m, err
+		}
+		m.A, m.B, m.C, m.D = v[0], v[1], v[2], v[3]
+	}
+	if mask&transformTranslate != 0 {
+		v, err := d.numbers(2)
+		if err != nil {
+			return m, err
+		}
+		m.E, m.F = v[0], v[1]
+	}
+	return m, nil
+}
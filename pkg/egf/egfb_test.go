@@ -0,0 +1,219 @@
+package egf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// drawing generates EGF text for a drawing of n entities covering every
+// shape kind, with the fractional coordinates typical of converted SVG
+func drawing(n int) string {
+	var b strings.Builder
+	b.WriteString("M(1920,1080,#fff)\n")
+	for i := 0; i < n; i++ {
+		x, y := float64(i%40)*47.25, float64(i/40)*31.5
+		switch i % 6 {
+		case 0:
+			fmt.Fprintf(&b, "H#%d = R(%g,%g,40.5,25.75) S(#2c3e50,#ff6b6b)\n", i, x, y)
+		case 1:
+			fmt.Fprintf(&b, "H#%d = C(%g,%g,12.125) S(#2c3e50,#4ecdc4)\n", i, x, y)
+		case 2:
+			fmt.Fprintf(&b, "H#%d = L(%g,%g,%g,%g) S(#e74c3c)\n", i, x, y, x+30.5, y+12.25)
+		case 3:
+			fmt.Fprintf(&b, "H#%d = E(%g,%g,18.5,9.25) S(#2c3e50,#f39c12)\n", i, x, y)
+		case 4:
+			fmt.Fprintf(&b, "H#%d = P[M %g %g Q %g %g %g %g Z] S(#8e44ad,none)\n", i, x, y, x+10.5, y-8.25, x+20, y)
+		case 5:
+			fmt.Fprintf(&b, "H#%d = PG[%g,%g %g,%g %g,%g] S(#2c3e50,#1abc9c)\n", i, x, y, x+15.5, y+20, x-15.5, y+20)
+		}
+	}
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "CALL#%d T(%g,%g,1,%d)\n", i, float64(i%7)*2.5, float64(i%5)*1.5, i%90)
+	}
+	return b.String()
+}
+
+// sources returns the EGF texts the size checks and benchmarks run on
+func sources(t testing.TB) map[string]string {
+	sample, err := ioutil.ReadFile("../../examples/sample.egf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{
+		"sample": string(sample),
+		"small":  drawing(60),
+		"large":  drawing(2000),
+	}
+}
+
+func mustParse(t testing.TB, src string) *Document {
+	t.Helper()
+	doc, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// TestEGFBSmallerThanText checks that EGFB is smaller than the canonical
+// EGF text of the same document, with and without compression
+func TestEGFBSmallerThanText(t *testing.T) {
+	for name, src := range sources(t) {
+		doc := mustParse(t, src)
+		text := len(Format(doc))
+		for _, opts := range []EncodeOptions{
+			{},
+			{Quantize: true, Decimals: 2},
+			{Compress: true},
+		} {
+			data, err := EncodeWithOptions(doc, opts)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if len(data) >= text {
+				t.Errorf("%s %+v: EGFB is %d bytes, EGF text %d", name, opts, len(data), text)
+			}
+			t.Logf("%s %+v: EGFB %d bytes, EGF %d bytes (%.0f%%)", name, opts, len(data), text, 100*float64(len(data))/float64(text))
+		}
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	for _, name := range []string{"sample", "large"} {
+		doc := mustParse(b, sources(b)[name])
+		for _, mode := range []struct {
+			name string
+			opts EncodeOptions
+		}{
+			{"exact", EncodeOptions{}},
+			{"compressed", EncodeOptions{Compress: true}},
+		} {
+			b.Run(name+"/"+mode.name, func(b *testing.B) {
+				var size int
+				for i := 0; i < b.N; i++ {
+					data, err := EncodeWithOptions(doc, mode.opts)
+					if err != nil {
+						b.Fatal(err)
+					}
+					size = len(data)
+				}
+				b.ReportMetric(float64(size), "egfb-bytes")
+				b.ReportMetric(float64(len(Format(doc))), "egf-bytes")
+			})
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	doc := mustParse(b, sources(b)["large"])
+	data, err := Encode(doc)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParse is the text baseline for BenchmarkDecode
+func BenchmarkParse(b *testing.B) {
+	src := Format(mustParse(b, sources(b)["large"]))
+	b.SetBytes(int64(len(src)))
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestEGFBRoundTrip checks that decoding an encoded document rebuilds the
+// same document, in every number encoding and file layout
+func TestEGFBRoundTrip(t *testing.T) {
+	sample, err := ioutil.ReadFile("../../examples/sample.egf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := []struct {
+		name string
+		src  string
+		// exact fixtures hold numbers that quantizing would change
+		exact bool
+		// v3 fixtures use records that need EGFB version 3
+		v3 bool
+	}{
+		{name: "sample", src: string(sample)},
+		{name: "drawing", src: drawing(30)},
+		{name: "varint", src: "R(0,-1,12.5,-0.25)\nC(1000000,0.001,3)\n"},
+		// 0.10000000149011612 is float32(0.1), stored as a float32; pi
+		// and 1e300 need a float64
+		{name: "float32", src: "R(0.10000000149011612,1,2,3)\n", exact: true},
+		{name: "float64", src: "C(3.141592653589793,1e300,-2.718281828459045)\n", exact: true},
+		{name: "transforms", src: "H#01 = C(0,0,1)\nCALL#01 T(5,5,1.5,30)\nCALL#01 T(1,2,2,0.5,15,10)\nCALL#01 TM(0.5,0.25,-0.25,0.5,3,4)\n", exact: true},
+		{name: "v3 styles", v3: true, src: `# header comment
+
+M(200,100,#fff)
+S#ink = S(#123,none,w=2.5,so=0.5,fo=0.25,dash=(4,2),cap=round,join=bevel,miter=8,rule=evenodd)
+H#01 = R(0,0,10,10) S#ink
+H#02 = G[
+  C(1,2,3) S(#f008)  # alpha color
+  P[M 0 0 L 10 10 A 5 5 0 1 0 20 20 Z] S(#000,#ff000080,w=1)
+  G[
+    PG[0,0 1,1 2,0]
+    PL[0,0 5,5]
+  ] S(#0f0,w=3)
+  CALL#01 T(1,1,1,0)
+] S(currentColor,#eee)
+CALL#02 T(0,0,1,0)
+`},
+	}
+	modes := []struct {
+		name string
+		opts EncodeOptions
+	}{
+		{"exact", EncodeOptions{}},
+		{"quantized", EncodeOptions{Quantize: true, Decimals: 3}},
+		{"checksum", EncodeOptions{Checksum: true}},
+		{"chunked checksum", EncodeOptions{Checksum: true, ChunkSize: 16}},
+		{"compressed", EncodeOptions{Compress: true}},
+		{"compressed and checksum", EncodeOptions{Compress: true, CompressionLevel: 9, Checksum: true, ChunkSize: 32}},
+		{"framed", EncodeOptions{Frame: true}},
+		{"framed, compressed and checksum", EncodeOptions{Frame: true, Compress: true, Checksum: true, ChunkSize: 32}},
+	}
+	for _, f := range fixtures {
+		doc := mustParse(t, f.src)
+		want := Format(doc)
+		for _, mode := range modes {
+			if f.exact && mode.opts.Quantize {
+				continue
+			}
+			t.Run(f.name+"/"+mode.name, func(t *testing.T) {
+				data, err := EncodeWithOptions(doc, mode.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				h, err := ReadHeader(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if wantVersion := map[bool]int{false: 2, true: 3}[f.v3]; h.Version != wantVersion {
+					t.Errorf("encoded as version %d, want %d", h.Version, wantVersion)
+				}
+				decoded, warnings, err := DecodeWithOptions(data, DecodeOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(warnings) != 0 {
+					t.Errorf("unexpected warnings: %v", warnings)
+				}
+				if got := Format(decoded); got != want {
+					t.Errorf("round trip changed the document\n got:\n%s\nwant:\n%s", got, want)
+				}
+			})
+		}
+	}
+}
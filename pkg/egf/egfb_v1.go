@@ -0,0 +1,45 @@
+package egf
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// decodeV1 reads the legacy line-encoded records of a version 1 file
+// starting at off: an opcode, a little-endian uint16 length and the EGF
+// line itself. The opcode only hinted at the command, so any value other
+// than the end mark is accepted. In lenient mode a damaged record ends
+// decoding with a warning and the lines before it are kept.
+func decodeV1(data []byte, off int, lenient bool) (string, []*DecodeError, error) {
+	r := &binReader{data: data, off: off}
+	var egf bytes.Buffer
+
+	fail := func(err error) (string, []*DecodeError, error) {
+		if !lenient {
+			return "", nil, err
+		}
+		return egf.String(), []*DecodeError{err.(*DecodeError)}, nil
+	}
+
+	for {
+		if r.off >= len(data) {
+			return fail(r.errorf(ErrMissingTerminator, "data ends without end marker 0x%02x", opEnd))
+		}
+		start := r.off
+		op, _ := r.byte()
+		if op == opEnd {
+			return egf.String(), nil, nil
+		}
+		header, err := r.bytes(2)
+		if err != nil {
+			return fail(r.errorAt(start, ErrTruncated, "record header cut short"))
+		}
+		length := int(binary.LittleEndian.Uint16(header))
+		line, err := r.bytes(length)
+		if err != nil {
+			return fail(r.errorAt(start+1, ErrBadLength, "line length %d exceeds the %d bytes remaining", length, len(data)-r.off))
+		}
+		egf.Write(line)
+		egf.WriteByte('\n')
+	}
+}
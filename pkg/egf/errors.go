@@ -0,0 +1,41 @@
+package egf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of EGFB corruption reported by DecodeError. Use errors.Is to test
+// for them; every DecodeError also matches ErrInvalidEGFB.
+var (
+	ErrTruncated         = errors.New("truncated record")
+	ErrUnknownOpcode     = errors.New("unknown opcode")
+	ErrBadLength         = errors.New("bad length")
+	ErrMissingTerminator = errors.New("missing end marker")
+	ErrChecksum          = errors.New("checksum mismatch")
+	ErrMalformed         = errors.New("malformed record")
+	ErrUnsupported       = errors.New("unsupported format")
+	ErrTooDeep           = errors.New("nesting too deep")
+)
+
+// DecodeError reports invalid EGFB data at a byte offset from the start
+// of the file
+type DecodeError struct {
+	Offset int
+	Kind   error
+	Msg    string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%v: offset %d: %s: %s", ErrInvalidEGFB, e.Offset, e.Kind, e.Msg)
+}
+
+// Unwrap returns the error kind
+func (e *DecodeError) Unwrap() error {
+	return e.Kind
+}
+
+// Is makes every DecodeError match ErrInvalidEGFB
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrInvalidEGFB
+}
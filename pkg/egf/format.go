@@ -0,0 +1,174 @@
+package egf
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/transform"
+)
+
+// indentUnit is the indentation used for each level of group nesting
+const indentUnit = "  "
+
+// Format serializes a document as canonical EGF text. Output is
+// deterministic: statements keep their document order, numbers use the
+// shortest exact representation, whitespace is normalized, runs of blank
+// lines collapse to one and comments are preserved.
+func Format(doc *Document) string {
+	var b strings.Builder
+	formatNodes(&b, doc.Nodes, "")
+	return b.String()
+}
+
+// WriteDocument writes the canonical form of a document to w
+func WriteDocument(w io.Writer, doc *Document) error {
+	_, err := io.WriteString(w, Format(doc))
+	return err
+}
+
+// FormatNode serializes a single statement without a trailing newline
+func FormatNode(n Node) string {
+	var b strings.Builder
+	writeNode(&b, n, "")
+	return b.String()
+}
+
+// FormatStyle serializes a style as S(...) or S#name
+func FormatStyle(s *Style) string {
+	var b strings.Builder
+	writeStyle(&b, s)
+	return strings.TrimPrefix(b.String(), " ")
+}
+
+// FormatSource parses EGF text and returns its canonical form
+func FormatSource(src string) (string, error) {
+	doc, err := Parse(src)
+	if err != nil {
+		return "", err
+	}
+	return Format(doc), nil
+}
+
+// formatNodes writes one statement per line at the given indentation
+func formatNodes(b *strings.Builder, nodes []Node, indent string) {
+	for i := 0; i < len(nodes); i++ {
+		n := nodes[i]
+		if i > 0 && n.base().blankBefore {
+			b.WriteString("\n")
+		}
+		b.WriteString(indent)
+		writeNode(b, n, indent)
+		if i+1 < len(nodes) {
+			if c, ok := nodes[i+1].(*Comment); ok && c.Trailing {
+				b.WriteString("  ")
+				writeComment(b, c)
+				i++
+			}
+		}
+		b.WriteString("\n")
+	}
+}
+
+func writeNode(b *strings.Builder, n Node, indent string) {
+	switch n := n.(type) {
+	case *Comment:
+		writeComment(b, n)
+	case *Canvas:
+		fmt.Fprintf(b, "M(%s,%s", FormatNumber(n.Width), FormatNumber(n.Height))
+		if n.Background != "" {
+			b.WriteString("," + n.Background)
+		}
+		b.WriteString(")")
+	case *EntityDef:
+		fmt.Fprintf(b, "H#%s = ", n.ID)
+		writeNode(b, n.Shape, indent)
+	case *StyleDef:
+		fmt.Fprintf(b, "S#%s =", n.Name)
+		writeStyle(b, n.Style)
+	case *Call:
+		fmt.Fprintf(b, "CALL#%s %s", n.ID, transform.Format(n.Transform))
+	case *Rect:
+		b.WriteString("R(" + formatNumbers(n.X, n.Y, n.Width, n.Height) + ")")
+		writeStyle(b, n.Style)
+	case *Circle:
+		b.WriteString("C(" + formatNumbers(n.Cx, n.Cy, n.R) + ")")
+		writeStyle(b, n.Style)
+	case *Line:
+		b.WriteString("L(" + formatNumbers(n.X1, n.Y1, n.X2, n.Y2) + ")")
+		writeStyle(b, n.Style)
+	case *Ellipse:
+		b.WriteString("E(" + formatNumbers(n.Cx, n.Cy, n.Rx, n.Ry) + ")")
+		writeStyle(b, n.Style)
+	case *Path:
+		b.WriteString("P[" + n.Data.String() + "]")
+		writeStyle(b, n.Style)
+	case *Polygon:
+		b.WriteString("PG[" + FormatPoints(n.Points) + "]")
+		writeStyle(b, n.Style)
+	case *Polyline:
+		b.WriteString("PL[" + FormatPoints(n.Points) + "]")
+		writeStyle(b, n.Style)
+	case *Group:
+		if len(n.Children) == 0 {
+			b.WriteString("G[]")
+		} else {
+			b.WriteString("G[\n")
+			formatNodes(b, n.Children, indent+indentUnit)
+			b.WriteString(indent + "]")
+		}
+		writeStyle(b, n.Style)
+	}
+}
+
+func writeComment(b *strings.Builder, c *Comment) {
+	if c.Text == "" {
+		b.WriteString("#")
+		return
+	}
+	b.WriteString("# " + c.Text)
+}
+
+func writeStyle(b *strings.Builder, s *Style) {
+	if s == nil {
+		return
+	}
+	if s.Ref != "" {
+		b.WriteString(" S#" + s.Ref)
+		return
+	}
+	b.WriteString(" S(" + s.Stroke)
+	if s.Fill != "" {
+		b.WriteString("," + s.Fill)
+	}
+	for _, opt := range s.options() {
+		b.WriteString("," + opt)
+	}
+	b.WriteString(")")
+}
+
+// FormatPoints serializes a point list as "x,y x,y ..."
+func FormatPoints(points []Point) string {
+	parts := make([]string, len(points))
+	for i, p := range points {
+		parts[i] = FormatNumber(p.X) + "," + FormatNumber(p.Y)
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatNumbers(values ...float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = FormatNumber(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+// FormatNumber formats a float using the shortest exact representation
+func FormatNumber(f float64) string {
+	if f == 0 {
+		return "0" // also normalizes negative zero
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
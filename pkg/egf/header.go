@@ -0,0 +1,143 @@
+package egf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// EGFB files start with the magic "EGFB". Version 1 files follow it
+// directly with line records (opcode, uint16 length, EGF text). Later
+// versions follow it with headerMarker, a version byte, a flags byte and
+// any flag-specific header fields, then typed records. headerMarker is
+// never a version 1 opcode, which keeps the two layouts unambiguous.
+const (
+	egfbMagic    = "EGFB"
+	headerMarker = 0xFE
+)
+
+// CurrentVersion is the newest EGFB format version. Version 3 adds the
+// extended and named styles, styled groups and colors with alpha. Encode
+// writes the oldest version that can hold a document, so version 2 readers
+// still open files that use none of these.
+const CurrentVersion = 3
+
+// Flags are the feature bits of an EGFB header
+type Flags uint8
+
+const (
+	// FlagPrecision marks numbers quantized to Header.Decimals places
+	FlagPrecision Flags = 1 << iota
+	// FlagChecksum marks a CRC32 trailer, optionally with one checksum per
+	// Header.ChunkSize bytes of records
+	FlagChecksum
+	// FlagCompressed marks a DEFLATE-compressed record stream
+	FlagCompressed
+	// FlagFramed marks top-level records prefixed with their length, which
+	// lets a lenient decoder skip a damaged record and carry on
+	FlagFramed
+)
+
+// supportedFlags are the flags this package can decode
+const supportedFlags = FlagPrecision | FlagChecksum | FlagCompressed | FlagFramed
+
+func (f Flags) String() string {
+	var names []string
+	for _, flag := range []struct {
+		bit  Flags
+		name string
+	}{
+		{FlagPrecision, "precision"},
+		{FlagChecksum, "checksum"},
+		{FlagCompressed, "compressed"},
+		{FlagFramed, "framed"},
+	} {
+		if f&flag.bit != 0 {
+			names = append(names, flag.name)
+			f &^= flag.bit
+		}
+	}
+	if f != 0 {
+		names = append(names, fmt.Sprintf("0x%02x", uint8(f)))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// Header describes the layout of an EGFB file
+type Header struct {
+	Version int
+	Flags   Flags
+	// Decimals is the quantization precision when FlagPrecision is set
+	Decimals int
+	// ChunkSize is the record span covered by each chunk checksum when
+	// FlagChecksum is set; zero means a single checksum for the file
+	ChunkSize int
+	// Size is the encoded header length in bytes
+	Size int
+}
+
+// ReadHeader parses and validates the header at the start of EGFB data
+func ReadHeader(data []byte) (Header, error) {
+	if len(data) < len(egfbMagic) || string(data[:len(egfbMagic)]) != egfbMagic {
+		return Header{}, &DecodeError{Kind: ErrMalformed, Msg: "missing EGFB magic"}
+	}
+	if len(data) == len(egfbMagic) || data[len(egfbMagic)] != headerMarker {
+		return Header{Version: 1, Size: len(egfbMagic)}, nil
+	}
+
+	r := &binReader{data: data, off: len(egfbMagic) + 1}
+	version, err := r.byte()
+	if err != nil {
+		return Header{}, err
+	}
+	flags, err := r.byte()
+	if err != nil {
+		return Header{}, err
+	}
+	h := Header{Version: int(version), Flags: Flags(flags)}
+	if h.Version < 2 || h.Version > CurrentVersion {
+		return Header{}, r.errorAt(len(egfbMagic)+1, ErrUnsupported, "EGFB version %d", h.Version)
+	}
+	if unknown := h.Flags &^ supportedFlags; unknown != 0 {
+		return Header{}, r.errorAt(len(egfbMagic)+2, ErrUnsupported, "EGFB flags %s", unknown)
+	}
+	if h.Flags&FlagPrecision != 0 {
+		decimals, err := r.byte()
+		if err != nil {
+			return Header{}, err
+		}
+		h.Decimals = int(decimals)
+	}
+	if h.Flags&FlagChecksum != 0 {
+		start := r.off
+		chunkSize, err := r.uvarint()
+		if err != nil {
+			return Header{}, err
+		}
+		if chunkSize > math.MaxInt32 {
+			return Header{}, r.errorAt(start, ErrBadLength, "checksum chunk size %d too large", chunkSize)
+		}
+		h.ChunkSize = int(chunkSize)
+	}
+	h.Size = r.off
+	return h, nil
+}
+
+// bytes encodes the header of a version 2+ file
+func (h Header) bytes() []byte {
+	var w binWriter
+	w.buf.WriteString(egfbMagic)
+	w.byte(headerMarker)
+	w.byte(byte(h.Version))
+	w.byte(byte(h.Flags))
+	if h.Flags&FlagPrecision != 0 {
+		w.byte(byte(h.Decimals))
+	}
+	if h.Flags&FlagChecksum != 0 {
+		w.uvarint(uint64(h.ChunkSize))
+	}
+	return w.buf.Bytes()
+}
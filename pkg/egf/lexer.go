@@ -0,0 +1,316 @@
+package egf
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// tokenKind identifies the lexical class of a token
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokComment
+	tokIdent
+	tokNumber
+	tokHash
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+	tokEquals
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokEOF:
+		return "end of file"
+	case tokNewline:
+		return "newline"
+	case tokComment:
+		return "comment"
+	case tokIdent:
+		return "identifier"
+	case tokNumber:
+		return "number"
+	case tokHash:
+		return "#id"
+	case tokLParen:
+		return `"("`
+	case tokRParen:
+		return `")"`
+	case tokLBracket:
+		return `"["`
+	case tokRBracket:
+		return `"]"`
+	case tokComma:
+		return `","`
+	case tokEquals:
+		return `"="`
+	default:
+		return "unknown token"
+	}
+}
+
+// token is a single lexical unit of EGF source
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+	// space reports whether whitespace separates the token from the one
+	// before it on the same line
+	space bool
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokIdent, tokNumber, tokHash:
+		return fmt.Sprintf("%s %q", t.kind, t.text)
+	default:
+		return t.kind.String()
+	}
+}
+
+// singleTokens maps one-byte punctuation to its token kind
+var singleTokens = map[byte]tokenKind{
+	'\n': tokNewline,
+	'(':  tokLParen,
+	')':  tokRParen,
+	'[':  tokLBracket,
+	']':  tokRBracket,
+	',':  tokComma,
+	'=':  tokEquals,
+}
+
+// lexer splits EGF source into tokens, tracking line and column
+type lexer struct {
+	src  string
+	off  int
+	line int
+	col  int
+	// prev is the previous token and depth the number of open parentheses,
+	// which decide whether a "#" starts an ID or a comment
+	prev  token
+	depth int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1, col: 1}
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{Line: l.line, Col: l.col}
+}
+
+// peekByte returns the byte at off+n, or 0 past the end of input
+func (l *lexer) peekByte(n int) byte {
+	if l.off+n >= len(l.src) {
+		return 0
+	}
+	return l.src[l.off+n]
+}
+
+// advance consumes one rune and updates the position
+func (l *lexer) advance() {
+	r, size := utf8.DecodeRuneInString(l.src[l.off:])
+	l.off += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+}
+
+// next returns the next token, skipping spaces, tabs and carriage returns
+func (l *lexer) next() (token, error) {
+	tok, err := l.scan()
+	if err != nil {
+		return tok, err
+	}
+	switch tok.kind {
+	case tokLParen:
+		l.depth++
+	case tokRParen:
+		if l.depth > 0 {
+			l.depth--
+		}
+	case tokNewline:
+		l.depth = 0
+	}
+	l.prev = tok
+	return tok, nil
+}
+
+// hashAllowed reports whether a "#" at the current position names an ID:
+// right after H, S or CALL, or inside the arguments of a command such as
+// S(#f00). Anywhere else it starts a comment.
+func (l *lexer) hashAllowed() bool {
+	if l.depth > 0 {
+		return true
+	}
+	if l.prev.kind != tokIdent {
+		return false
+	}
+	switch l.prev.text {
+	case "H", "S", "CALL":
+		return true
+	}
+	return false
+}
+
+func (l *lexer) scan() (token, error) {
+	begin := l.off
+	for l.off < len(l.src) {
+		c := l.src[l.off]
+		if c != ' ' && c != '\t' && c != '\r' {
+			break
+		}
+		l.advance()
+	}
+	space := l.off > begin
+
+	start := l.pos()
+	if l.off >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	c := l.src[l.off]
+	if kind, ok := singleTokens[c]; ok {
+		l.advance()
+		return token{kind: kind, text: string(c), pos: start, space: space}, nil
+	}
+
+	begin = l.off
+	switch {
+	case c == '#':
+		l.advance()
+		if !l.hashAllowed() || !isIdentByte(l.peekByte(0)) {
+			// Any other "#" starts a comment
+			for l.off < len(l.src) && l.src[l.off] != '\n' {
+				l.advance()
+			}
+			return token{kind: tokComment, text: l.src[begin:l.off], pos: start}, nil
+		}
+		for isIdentByte(l.peekByte(0)) {
+			l.advance()
+		}
+		return token{kind: tokHash, text: l.src[begin:l.off], pos: start, space: space}, nil
+
+	case isLetter(c):
+		for isLetter(l.peekByte(0)) {
+			l.advance()
+		}
+		return token{kind: tokIdent, text: l.src[begin:l.off], pos: start, space: space}, nil
+
+	case isDigit(c) || c == '.' || c == '-' || c == '+':
+		l.scanNumber()
+		if l.off == begin {
+			l.advance()
+			return token{}, &ParseError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+		return token{kind: tokNumber, text: l.src[begin:l.off], pos: start, space: space}, nil
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.src[l.off:])
+	l.advance()
+	return token{}, &ParseError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", r)}
+}
+
+// scanNumber consumes a decimal number with optional sign, fraction,
+// exponent and a trailing "%" (used by color functions such as hsl)
+func (l *lexer) scanNumber() {
+	begin := l.off
+	if c := l.peekByte(0); c == '-' || c == '+' {
+		l.advance()
+	}
+	digits := 0
+	for isDigit(l.peekByte(0)) {
+		l.advance()
+		digits++
+	}
+	if l.peekByte(0) == '.' {
+		l.advance()
+		for isDigit(l.peekByte(0)) {
+			l.advance()
+			digits++
+		}
+	}
+	if digits == 0 {
+		l.reset(begin)
+		return
+	}
+	if c := l.peekByte(0); c == 'e' || c == 'E' {
+		n := 1
+		if s := l.peekByte(1); s == '-' || s == '+' {
+			n = 2
+		}
+		if isDigit(l.peekByte(n)) {
+			for i := 0; i < n; i++ {
+				l.advance()
+			}
+			for isDigit(l.peekByte(0)) {
+				l.advance()
+			}
+		}
+	}
+	if l.peekByte(0) == '%' {
+		l.advance()
+	}
+}
+
+// reset rewinds the lexer to a byte offset on the current line
+func (l *lexer) reset(off int) {
+	l.col -= utf8.RuneCountInString(l.src[off:l.off])
+	l.off = off
+}
+
+// rawUntil consumes raw text up to (not including) the closing delimiter,
+// used for P[...], PG[...] and PL[...] bodies which have their own syntax
+func (l *lexer) rawUntil(close byte) (string, error) {
+	start := l.pos()
+	begin := l.off
+	for l.off < len(l.src) && l.src[l.off] != close {
+		l.advance()
+	}
+	if l.off >= len(l.src) {
+		return "", &ParseError{Pos: start, Msg: fmt.Sprintf("unterminated block, missing %q", close)}
+	}
+	return l.src[begin:l.off], nil
+}
+
+// rawParens consumes raw text up to (not including) the ")" closing a "("
+// that was just read, skipping nested pairs. It keeps the source of nested
+// lists such as rgb(255 0 0 / 50%) intact, which EGF tokens cannot spell.
+func (l *lexer) rawParens() (string, error) {
+	start := l.pos()
+	begin := l.off
+	depth := 0
+	for l.off < len(l.src) && l.src[l.off] != '\n' {
+		switch l.src[l.off] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return l.src[begin:l.off], nil
+			}
+			depth--
+		}
+		l.advance()
+	}
+	return "", &ParseError{Pos: start, Msg: `unterminated "(", missing ")"`}
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '_' || c == '-'
+}
@@ -0,0 +1,697 @@
+package egf
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/color"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/pathdata"
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/transform"
+)
+
+// ParseError reports a syntax error at a position in EGF source
+type ParseError struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Pos.Line, e.Pos.Col, e.Msg)
+}
+
+// Parse parses EGF source text into a Document
+func Parse(src string) (*Document, error) {
+	p := &parser{lex: newLexer(src)}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return p.document()
+}
+
+// ParseReader reads EGF source from r and parses it into a Document
+func ParseReader(r io.Reader) (*Document, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(src))
+}
+
+// ReadDocument reads and parses an EGF file
+func ReadDocument(filename string) (*Document, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseReader(f)
+}
+
+// parser is a recursive-descent parser with one token of lookahead
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// next advances to the following token
+func (p *parser) next() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(pos Pos, format string, args ...interface{}) error {
+	return &ParseError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// expect consumes the current token if it has the given kind
+func (p *parser) expect(kind tokenKind) (token, error) {
+	tok := p.tok
+	if tok.kind != kind {
+		return tok, p.errorf(tok.pos, "expected %s, found %s", kind, tok)
+	}
+	return tok, p.next()
+}
+
+func (p *parser) document() (*Document, error) {
+	doc := &Document{}
+	newlines := 0
+	for p.tok.kind != tokEOF {
+		switch p.tok.kind {
+		case tokNewline:
+			newlines++
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			continue
+		case tokComment:
+			c := p.comment(false)
+			c.blankBefore = newlines > 1
+			doc.Nodes = append(doc.Nodes, c)
+			newlines = 0
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		n, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		n.base().blankBefore = newlines > 1
+		doc.Nodes = append(doc.Nodes, n)
+		newlines = 0
+
+		if p.tok.kind == tokComment {
+			doc.Nodes = append(doc.Nodes, p.comment(true))
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if p.tok.kind != tokNewline && p.tok.kind != tokEOF {
+			return nil, p.errorf(p.tok.pos, "expected end of line, found %s", p.tok)
+		}
+	}
+	return doc, nil
+}
+
+// comment converts the current comment token into a Comment node
+func (p *parser) comment(trailing bool) *Comment {
+	text := strings.TrimSpace(strings.TrimPrefix(p.tok.text, "#"))
+	return &Comment{node: node{pos: p.tok.pos}, Text: text, Trailing: trailing}
+}
+
+// statement parses one top-level statement
+func (p *parser) statement() (Node, error) {
+	if p.tok.kind != tokIdent {
+		return nil, p.errorf(p.tok.pos, "expected command, found %s", p.tok)
+	}
+	switch p.tok.text {
+	case "M":
+		return p.canvas()
+	case "H":
+		return p.entityDef()
+	case "S":
+		return p.styleDef()
+	case "CALL":
+		return p.call()
+	default:
+		return p.shape()
+	}
+}
+
+func (p *parser) canvas() (*Canvas, error) {
+	c := &Canvas{node: node{pos: p.tok.pos}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	args, err := p.valueList("M")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 2 || len(args) > 3 {
+		return nil, p.errorf(c.pos, "M expects 2 or 3 arguments, got %d", len(args))
+	}
+	if c.Width, err = p.number(args[0]); err != nil {
+		return nil, err
+	}
+	if c.Height, err = p.number(args[1]); err != nil {
+		return nil, err
+	}
+	if len(args) == 3 {
+		if c.Background, err = p.color(args[2]); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (p *parser) entityDef() (*EntityDef, error) {
+	def := &EntityDef{node: node{pos: p.tok.pos}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	id, err := p.expect(tokHash)
+	if err != nil {
+		return nil, err
+	}
+	def.ID = strings.TrimPrefix(id.text, "#")
+	if _, err := p.expect(tokEquals); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokIdent {
+		return nil, p.errorf(p.tok.pos, "expected shape after H#%s =, found %s", def.ID, p.tok)
+	}
+	if def.Shape, err = p.shape(); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+func (p *parser) styleDef() (*StyleDef, error) {
+	def := &StyleDef{node: node{pos: p.tok.pos}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	name, err := p.expect(tokHash)
+	if err != nil {
+		return nil, err
+	}
+	def.Name = strings.TrimPrefix(name.text, "#")
+	if _, err := p.expect(tokEquals); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokIdent || p.tok.text != "S" {
+		return nil, p.errorf(p.tok.pos, "expected S(...) after S#%s =, found %s", def.Name, p.tok)
+	}
+	if def.Style, err = p.style(); err != nil {
+		return nil, err
+	}
+	if def.Style.Ref != "" {
+		return nil, p.errorf(def.pos, "S#%s must be defined with S(...), not another named style", def.Name)
+	}
+	return def, nil
+}
+
+func (p *parser) call() (*Call, error) {
+	call := &Call{node: node{pos: p.tok.pos}, Transform: transform.Identity()}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	id, err := p.expect(tokHash)
+	if err != nil {
+		return nil, err
+	}
+	call.ID = strings.TrimPrefix(id.text, "#")
+
+	if p.tok.kind == tokIdent && (p.tok.text == "T" || p.tok.text == "TM") {
+		if call.Transform, err = p.transform(); err != nil {
+			return nil, err
+		}
+	}
+	return call, nil
+}
+
+// transform parses T(...) or TM(...) into a matrix
+func (p *parser) transform() (transform.Matrix, error) {
+	pos, name := p.tok.pos, p.tok.text
+	if err := p.next(); err != nil {
+		return transform.Matrix{}, err
+	}
+	args, err := p.valueList(name)
+	if err != nil {
+		return transform.Matrix{}, err
+	}
+	v := make([]float64, len(args))
+	for i, a := range args {
+		if v[i], err = p.number(a); err != nil {
+			return transform.Matrix{}, err
+		}
+	}
+	m, err := transform.FromArgs(name, v)
+	if err != nil {
+		return transform.Matrix{}, p.errorf(pos, "%v", err)
+	}
+	return m, nil
+}
+
+// shape parses a drawable primitive and its optional style
+func (p *parser) shape() (Shape, error) {
+	pos := p.tok.pos
+	cmd := p.tok.text
+	if p.tok.kind != tokIdent {
+		return nil, p.errorf(pos, "expected shape, found %s", p.tok)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	var s Shape
+	switch cmd {
+	case "R":
+		v, err := p.numbers(cmd, pos, 4)
+		if err != nil {
+			return nil, err
+		}
+		s = &Rect{node: node{pos: pos}, X: v[0], Y: v[1], Width: v[2], Height: v[3]}
+	case "C":
+		v, err := p.numbers(cmd, pos, 3)
+		if err != nil {
+			return nil, err
+		}
+		s = &Circle{node: node{pos: pos}, Cx: v[0], Cy: v[1], R: v[2]}
+	case "L":
+		v, err := p.numbers(cmd, pos, 4)
+		if err != nil {
+			return nil, err
+		}
+		s = &Line{node: node{pos: pos}, X1: v[0], Y1: v[1], X2: v[2], Y2: v[3]}
+	case "E":
+		v, err := p.numbers(cmd, pos, 4)
+		if err != nil {
+			return nil, err
+		}
+		s = &Ellipse{node: node{pos: pos}, Cx: v[0], Cy: v[1], Rx: v[2], Ry: v[3]}
+	case "P":
+		raw, rawPos, err := p.rawBlock()
+		if err != nil {
+			return nil, err
+		}
+		data, err := pathdata.Parse(raw)
+		if err != nil {
+			if se, ok := err.(*pathdata.SyntaxError); ok {
+				return nil, &ParseError{Pos: offsetPos(raw, rawPos, se.Offset), Msg: se.Msg}
+			}
+			return nil, err
+		}
+		s = &Path{node: node{pos: pos}, Data: data}
+	case "PG", "PL":
+		raw, rawPos, err := p.rawBlock()
+		if err != nil {
+			return nil, err
+		}
+		points, err := parsePoints(raw, rawPos)
+		if err != nil {
+			return nil, err
+		}
+		if cmd == "PG" {
+			s = &Polygon{node: node{pos: pos}, Points: points}
+		} else {
+			s = &Polyline{node: node{pos: pos}, Points: points}
+		}
+	case "G":
+		g, err := p.group(pos)
+		if err != nil {
+			return nil, err
+		}
+		s = g
+	default:
+		return nil, p.errorf(pos, "unknown command %q", cmd)
+	}
+
+	if p.tok.kind == tokIdent && p.tok.text == "S" {
+		style, err := p.style()
+		if err != nil {
+			return nil, err
+		}
+		SetStyle(s, style)
+	}
+	return s, nil
+}
+
+// SetStyle attaches a style to any styled shape or group
+func SetStyle(s Shape, style *Style) {
+	switch v := s.(type) {
+	case *Group:
+		v.Style = style
+	case *Rect:
+		v.Style = style
+	case *Circle:
+		v.Style = style
+	case *Line:
+		v.Style = style
+	case *Ellipse:
+		v.Style = style
+	case *Path:
+		v.Style = style
+	case *Polygon:
+		v.Style = style
+	case *Polyline:
+		v.Style = style
+	}
+}
+
+// StyleOf returns the style written on a shape or group, or nil
+func StyleOf(s Shape) *Style {
+	switch v := s.(type) {
+	case *Group:
+		return v.Style
+	case *Rect:
+		return v.Style
+	case *Circle:
+		return v.Style
+	case *Line:
+		return v.Style
+	case *Ellipse:
+		return v.Style
+	case *Path:
+		return v.Style
+	case *Polygon:
+		return v.Style
+	case *Polyline:
+		return v.Style
+	}
+	return nil
+}
+
+func (p *parser) style() (*Style, error) {
+	pos := p.tok.pos
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokHash {
+		ref := strings.TrimPrefix(p.tok.text, "#")
+		return &Style{Ref: ref}, p.next()
+	}
+	args, err := p.valueList("S")
+	if err != nil {
+		return nil, err
+	}
+	// Up to two positional colors come first, then key=value options
+	positional := 0
+	for positional < len(args) && !strings.Contains(args[positional].text, "=") {
+		positional++
+	}
+	if positional < 1 || positional > 2 {
+		return nil, p.errorf(pos, "S expects 1 or 2 colors before its options, got %d", positional)
+	}
+	style := &Style{}
+	if style.Stroke, err = p.color(args[0]); err != nil {
+		return nil, err
+	}
+	if positional == 2 {
+		if style.Fill, err = p.color(args[1]); err != nil {
+			return nil, err
+		}
+	}
+	seen := map[string]bool{}
+	for _, a := range args[positional:] {
+		eq := strings.IndexByte(a.text, '=')
+		if eq == -1 {
+			return nil, p.errorf(a.pos, "S colors must come before its options, found %q", a.text)
+		}
+		key := strings.TrimSpace(a.text[:eq])
+		v := value{text: strings.TrimSpace(a.text[eq+1:]), pos: a.pos}
+		if seen[key] {
+			return nil, p.errorf(a.pos, "duplicate S option %q", key)
+		}
+		seen[key] = true
+		if err := p.styleOption(style, key, v); err != nil {
+			return nil, err
+		}
+	}
+	if err := style.Validate(); err != nil {
+		return nil, p.errorf(pos, "invalid style: %v", err)
+	}
+	return style, nil
+}
+
+// styleOption sets the style field named by an S option key
+func (p *parser) styleOption(s *Style, key string, v value) error {
+	number := func(field **float64) error {
+		f, err := p.number(v)
+		if err != nil {
+			return err
+		}
+		*field = &f
+		return nil
+	}
+	switch key {
+	case "w":
+		return number(&s.Width)
+	case "so":
+		return number(&s.StrokeOpacity)
+	case "fo":
+		return number(&s.FillOpacity)
+	case "miter":
+		return number(&s.MiterLimit)
+	case "cap":
+		s.Cap = v.text
+	case "join":
+		s.Join = v.text
+	case "rule":
+		s.FillRule = v.text
+	case "dash":
+		if !strings.HasPrefix(v.text, "(") || !strings.HasSuffix(v.text, ")") || len(v.text) == 2 {
+			return p.errorf(v.pos, "dash expects a list such as (4,2), found %q", v.text)
+		}
+		for _, item := range strings.Split(v.text[1:len(v.text)-1], ",") {
+			f, err := p.number(value{text: strings.TrimSpace(item), pos: v.pos})
+			if err != nil {
+				return err
+			}
+			s.Dash = append(s.Dash, f)
+		}
+	default:
+		return p.errorf(v.pos, "unknown S option %q", key)
+	}
+	return nil
+}
+
+// group parses G[...] whose body is a sequence of shapes, calls and comments
+func (p *parser) group(pos Pos) (*Group, error) {
+	g := &Group{node: node{pos: pos}}
+	if _, err := p.expect(tokLBracket); err != nil {
+		return nil, err
+	}
+	// newlines counts line breaks since the previous child; a comment with
+	// none before it trails that child on the same line
+	newlines := 1
+	for {
+		switch p.tok.kind {
+		case tokRBracket:
+			return g, p.next()
+		case tokEOF:
+			return nil, p.errorf(pos, "unterminated group, missing \"]\"")
+		case tokNewline:
+			newlines++
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			continue
+		case tokComment:
+			c := p.comment(newlines == 0 && len(g.Children) > 0)
+			c.blankBefore = newlines > 1
+			g.Children = append(g.Children, c)
+			newlines = 0
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var (
+			n   Node
+			err error
+		)
+		if p.tok.kind == tokIdent && p.tok.text == "CALL" {
+			n, err = p.call()
+		} else {
+			n, err = p.shape()
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.base().blankBefore = newlines > 1 && len(g.Children) > 0
+		g.Children = append(g.Children, n)
+		newlines = 0
+	}
+}
+
+// rawBlock reads the verbatim body of a [...] block
+func (p *parser) rawBlock() (string, Pos, error) {
+	if p.tok.kind != tokLBracket {
+		return "", p.tok.pos, p.errorf(p.tok.pos, "expected %s, found %s", tokLBracket, p.tok)
+	}
+	pos := p.lex.pos()
+	raw, err := p.lex.rawUntil(']')
+	if err != nil {
+		return "", pos, err
+	}
+	if err := p.next(); err != nil {
+		return "", pos, err
+	}
+	if _, err := p.expect(tokRBracket); err != nil {
+		return "", pos, err
+	}
+	return raw, pos, nil
+}
+
+// value is one argument of a (...) list, kept as source text
+type value struct {
+	text string
+	pos  Pos
+}
+
+// valueList parses "(" value { "," value } ")". A value may contain a
+// nested list, e.g. rgb(255 0 0 / 50%), which is kept as written so its
+// commas do not split arguments. Whitespace between the other tokens of a
+// value is kept as a single space.
+func (p *parser) valueList(cmd string) ([]value, error) {
+	if _, err := p.expect(tokLParen); err != nil {
+		return nil, err
+	}
+	var (
+		args  []value
+		cur   strings.Builder
+		start = p.tok.pos
+	)
+	for {
+		tok := p.tok
+		if tok.space && cur.Len() > 0 {
+			cur.WriteByte(' ')
+		}
+		switch tok.kind {
+		case tokEOF, tokNewline:
+			return nil, p.errorf(tok.pos, "unterminated %s(...), found %s", cmd, tok)
+		case tokLParen:
+			raw, err := p.lex.rawParens()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			cur.WriteString("(" + raw + ")")
+		case tokRParen, tokComma:
+			if cur.Len() == 0 {
+				return nil, p.errorf(tok.pos, "empty argument in %s(...)", cmd)
+			}
+			args = append(args, value{text: strings.TrimSpace(cur.String()), pos: start})
+			cur.Reset()
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if tok.kind == tokRParen {
+				return args, nil
+			}
+			start = p.tok.pos
+			continue
+		case tokComment:
+			return nil, p.errorf(tok.pos, "unexpected comment in %s(...)", cmd)
+		default:
+			cur.WriteString(tok.text)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// numbers parses a parenthesised list of exactly n numbers
+func (p *parser) numbers(cmd string, pos Pos, n int) ([]float64, error) {
+	args, err := p.valueList(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != n {
+		return nil, p.errorf(pos, "%s expects %d arguments, got %d", cmd, n, len(args))
+	}
+	out := make([]float64, n)
+	for i, a := range args {
+		if out[i], err = p.number(a); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// color parses a color argument into its canonical form
+func (p *parser) color(v value) (string, error) {
+	c, err := color.Normalize(v.text)
+	if err != nil {
+		return "", p.errorf(v.pos, "%v", err)
+	}
+	return c, nil
+}
+
+func (p *parser) number(v value) (float64, error) {
+	if strings.Contains(v.text, " ") {
+		return 0, p.errorf(v.pos, "missing \",\" between numbers in %q", v.text)
+	}
+	f, err := strconv.ParseFloat(v.text, 64)
+	if err != nil {
+		return 0, p.errorf(v.pos, "invalid number %q", v.text)
+	}
+	return f, nil
+}
+
+// offsetPos returns the position of a byte offset within raw text that
+// starts at pos
+func offsetPos(raw string, pos Pos, offset int) Pos {
+	for _, r := range raw[:offset] {
+		if r == '\n' {
+			pos.Line++
+			pos.Col = 1
+		} else {
+			pos.Col++
+		}
+	}
+	return pos
+}
+
+// ParsePoints parses an "x,y x,y ..." point list as used by PG[...] and PL[...]
+func ParsePoints(s string) ([]Point, error) {
+	return parsePoints(s, Pos{})
+}
+
+// parsePoints parses a point list, reporting errors at pos
+func parsePoints(raw string, pos Pos) ([]Point, error) {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	if len(fields)%2 != 0 {
+		return nil, &ParseError{Pos: pos, Msg: fmt.Sprintf("odd number of coordinates in point list (%d)", len(fields))}
+	}
+	points := make([]Point, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		x, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, &ParseError{Pos: pos, Msg: fmt.Sprintf("invalid number %q in point list", fields[i])}
+		}
+		y, err := strconv.ParseFloat(fields[i+1], 64)
+		if err != nil {
+			return nil, &ParseError{Pos: pos, Msg: fmt.Sprintf("invalid number %q in point list", fields[i+1])}
+		}
+		points = append(points, Point{X: x, Y: y})
+	}
+	return points, nil
+}
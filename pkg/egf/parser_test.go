@@ -0,0 +1,91 @@
+package egf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"trailing comment", "R(0,0,1,1) # note", "R(0,0,1,1)  # note\n"},
+		{"comment lines and comments without space", "#comment\nR(0,0,1,1)#x", "# comment\nR(0,0,1,1)  # x\n"},
+		{"# names entities after H and CALL", "H#a1 = C(1,2,3)\nCALL#a1 T(0,0,1,0)", "H#a1 = C(1,2,3)\nCALL#a1 T(0,0,1,0)\n"},
+		{"# names styles after S", "S#a = S(#000)\nR(0,0,1,1) S#a", "S#a = S(#000)\nR(0,0,1,1) S#a\n"},
+		{"# starts colors inside arguments", "R(0,0,1,1) S(#F00,#00ff00)", "R(0,0,1,1) S(#f00,#0f0)\n"},
+		{"several statements on one line in a group", "G[R(0,0,1,1) C(1,1,1) CALL#a T(0,0,1,0)] S(#000)", "G[\n  R(0,0,1,1)\n  C(1,1,1)\n  CALL#a T(0,0,1,0)\n] S(#000)\n"},
+		{"comment inside a group", "G[\n  R(0,0,1,1) # a\n  C(1,1,1)\n]", "G[\n  R(0,0,1,1)  # a\n  C(1,1,1)\n]\n"},
+		{"percentages in color functions", "R(0,0,1,1) S(hsl(0,100%,50%),hsl(120 100% 25% / 50%))", "R(0,0,1,1) S(#f00,#00800080)\n"},
+		{"nested parentheses kept as written", "R(0,0,1,1) S(rgb(255 0 0 / 50%),rgba(0, 0, 255, 0.5))", "R(0,0,1,1) S(#ff000080,#0000ff80)\n"},
+		{"spaces around arguments", "R( 0 , 0 ,1, 1 )", "R(0,0,1,1)\n"},
+		{"style options", "L(0,0,1,1) S(#000, w = 2, dash=(4, 2), cap=round)", "L(0,0,1,1) S(#000,w=2,dash=(4,2),cap=round)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Format(doc); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src       string
+		line, col int
+		msg       string
+	}{
+		{"R(0 1,2,3,4)", 1, 3, `missing "," between numbers in "0 1"`},
+		{"R(50%,0,1,1)", 1, 3, `invalid number "50%"`},
+		{"M(1)", 1, 1, "M expects 2 or 3 arguments, got 1"},
+		{"R(0,0,1)", 1, 1, "R expects 4 arguments, got 3"},
+		{"R(0,0,1,1) S()", 1, 14, "empty argument in S(...)"},
+		{"R(0,0,1,1) S(#000,#fff,#f00)", 1, 12, "S expects 1 or 2 colors before its options, got 3"},
+		{"R(0,0,1,1) S(w=2)", 1, 12, "S expects 1 or 2 colors before its options, got 0"},
+		{"R(0,0,1,1) S(#000,w=2,#fff)", 1, 23, `S colors must come before its options, found "#fff"`},
+		{"R(0,0,1,1) S(#000,w=1,w=2)", 1, 23, `duplicate S option "w"`},
+		{"R(0,0,1,1) S(#000,dash=4)", 1, 19, "dash expects a list such as (4,2)"},
+		{"R(0,0,1,1) S(#000\n)", 1, 18, "unterminated S(...)"},
+		{"\nG[R(0,0,1,1)", 2, 1, `unterminated group, missing "]"`},
+		{"R(0,0,1,1) C(1,1,1)", 1, 12, "expected end of line"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("Parse(%q) error = %v, want a ParseError", tt.src, err)
+			continue
+		}
+		if perr.Pos.Line != tt.line || perr.Pos.Col != tt.col || !strings.Contains(perr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) error = %v, want line %d, column %d: %s", tt.src, err, tt.line, tt.col, tt.msg)
+		}
+	}
+}
+
+// TestFormatIdempotent checks that formatted output parses back to a
+// document that formats identically
+func TestFormatIdempotent(t *testing.T) {
+	srcs := sources(t)
+	srcs["styles and comments"] = `# header
+
+M(200,100,#fff)
+S#ink = S(#123,none,w=2.5,dash=(4,2),cap=round)  # ink
+H#01 = G[
+  C(1,2,3) S(#f008)
+
+  P[M 0 0 L 10 10 A 5 5 0 1 0 20 20 Z] S#ink
+] S(currentColor,#eee)
+CALL#01 TM(0.5,0.25,-0.25,0.5,3,4)
+`
+	for name, src := range srcs {
+		once := Format(mustParse(t, src))
+		if twice := Format(mustParse(t, once)); twice != once {
+			t.Errorf("%s: formatting is not idempotent\nonce:\n%s\ntwice:\n%s", name, once, twice)
+		}
+	}
+}
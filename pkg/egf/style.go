@@ -0,0 +1,139 @@
+package egf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keyword values of the enumerated style options, in EGFB encoding order
+var (
+	lineCaps  = []string{"butt", "round", "square"}
+	lineJoins = []string{"miter", "round", "bevel"}
+	fillRules = []string{"nonzero", "evenodd"}
+)
+
+// enumIndex returns the position of v in values, or -1
+func enumIndex(values []string, v string) int {
+	for i, s := range values {
+		if s == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validate reports the first style option outside its allowed range
+func (s *Style) Validate() error {
+	if s.Ref != "" && (s.Stroke != "" || s.Fill != "" || len(s.options()) > 0) {
+		return fmt.Errorf("reference to S#%s cannot set other fields", s.Ref)
+	}
+	if s.Width != nil && *s.Width < 0 {
+		return fmt.Errorf("stroke width %s is negative", FormatNumber(*s.Width))
+	}
+	for _, o := range []struct {
+		name string
+		v    *float64
+	}{{"stroke opacity", s.StrokeOpacity}, {"fill opacity", s.FillOpacity}} {
+		if o.v != nil && (*o.v < 0 || *o.v > 1) {
+			return fmt.Errorf("%s %s is outside 0 to 1", o.name, FormatNumber(*o.v))
+		}
+	}
+	for _, v := range s.Dash {
+		if v < 0 {
+			return fmt.Errorf("dash length %s is negative", FormatNumber(v))
+		}
+	}
+	if s.MiterLimit != nil && *s.MiterLimit < 1 {
+		return fmt.Errorf("miter limit %s is less than 1", FormatNumber(*s.MiterLimit))
+	}
+	for _, o := range []struct {
+		name, v string
+		values  []string
+	}{{"line cap", s.Cap, lineCaps}, {"line join", s.Join, lineJoins}, {"fill rule", s.FillRule, fillRules}} {
+		if o.v != "" && enumIndex(o.values, o.v) == -1 {
+			return fmt.Errorf("unknown %s %q, expected one of %s", o.name, o.v, strings.Join(o.values, ", "))
+		}
+	}
+	return nil
+}
+
+// options returns the key=value arguments of a style in canonical order
+func (s *Style) options() []string {
+	var opts []string
+	number := func(key string, v *float64) {
+		if v != nil {
+			opts = append(opts, key+"="+FormatNumber(*v))
+		}
+	}
+	word := func(key, v string) {
+		if v != "" {
+			opts = append(opts, key+"="+v)
+		}
+	}
+	number("w", s.Width)
+	number("so", s.StrokeOpacity)
+	number("fo", s.FillOpacity)
+	if len(s.Dash) > 0 {
+		dash := make([]string, len(s.Dash))
+		for i, v := range s.Dash {
+			dash[i] = FormatNumber(v)
+		}
+		opts = append(opts, "dash=("+strings.Join(dash, ",")+")")
+	}
+	word("cap", s.Cap)
+	word("join", s.Join)
+	number("miter", s.MiterLimit)
+	word("rule", s.FillRule)
+	return opts
+}
+
+// ResolveStyle returns the style in effect for a shape: its own style, with
+// a reference replaced by the named definition, and any field it leaves
+// unset taken from the style inherited from the enclosing groups. It
+// returns nil when neither is set.
+func ResolveStyle(own, inherited *Style, named map[string]*Style) (*Style, error) {
+	if own != nil && own.Ref != "" {
+		style, ok := named[own.Ref]
+		if !ok {
+			return nil, fmt.Errorf("S#%s references undefined style", own.Ref)
+		}
+		own = style
+	}
+	if own == nil || inherited == nil {
+		if own == nil {
+			return inherited, nil
+		}
+		return own, nil
+	}
+
+	merged := *own
+	if merged.Stroke == "" {
+		merged.Stroke = inherited.Stroke
+	}
+	if merged.Fill == "" {
+		merged.Fill = inherited.Fill
+	}
+	for _, f := range []struct{ field, from **float64 }{
+		{&merged.Width, &inherited.Width},
+		{&merged.StrokeOpacity, &inherited.StrokeOpacity},
+		{&merged.FillOpacity, &inherited.FillOpacity},
+		{&merged.MiterLimit, &inherited.MiterLimit},
+	} {
+		if *f.field == nil {
+			*f.field = *f.from
+		}
+	}
+	if len(merged.Dash) == 0 {
+		merged.Dash = inherited.Dash
+	}
+	for _, f := range []struct{ field, from *string }{
+		{&merged.Cap, &inherited.Cap},
+		{&merged.Join, &inherited.Join},
+		{&merged.FillRule, &inherited.FillRule},
+	} {
+		if *f.field == "" {
+			*f.field = *f.from
+		}
+	}
+	return &merged, nil
+}
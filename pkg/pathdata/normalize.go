@@ -0,0 +1,178 @@
+package pathdata
+
+import "math"
+
+// ExpandShorthands returns an absolute copy of the path in which H and V
+// become L, S becomes C and T becomes Q with their implied control points
+// made explicit
+func (p Path) ExpandShorthands() Path {
+	abs := p.ToAbsolute()
+	out := make(Path, 0, len(abs))
+	var cx, cy, startX, startY float64
+	// Control points of the previous C/S and Q/T, used for reflection
+	var cubicX, cubicY, quadX, quadY float64
+	prev := byte(0)
+
+	for _, seg := range abs {
+		args := seg.Args
+		switch seg.Cmd {
+		case 'M':
+			cx, cy = args[0], args[1]
+			startX, startY = cx, cy
+			out = append(out, seg)
+		case 'L':
+			cx, cy = args[0], args[1]
+			out = append(out, seg)
+		case 'H':
+			cx = args[0]
+			out = append(out, Segment{Cmd: 'L', Args: []float64{cx, cy}})
+		case 'V':
+			cy = args[0]
+			out = append(out, Segment{Cmd: 'L', Args: []float64{cx, cy}})
+		case 'C':
+			cubicX, cubicY = args[2], args[3]
+			cx, cy = args[4], args[5]
+			out = append(out, seg)
+		case 'S':
+			x1, y1 := cx, cy
+			if prev == 'C' || prev == 'S' {
+				x1, y1 = 2*cx-cubicX, 2*cy-cubicY
+			}
+			cubicX, cubicY = args[0], args[1]
+			cx, cy = args[2], args[3]
+			out = append(out, Segment{Cmd: 'C', Args: []float64{x1, y1, args[0], args[1], cx, cy}})
+		case 'Q':
+			quadX, quadY = args[0], args[1]
+			cx, cy = args[2], args[3]
+			out = append(out, seg)
+		case 'T':
+			x1, y1 := cx, cy
+			if prev == 'Q' || prev == 'T' {
+				x1, y1 = 2*cx-quadX, 2*cy-quadY
+			}
+			quadX, quadY = x1, y1
+			cx, cy = args[0], args[1]
+			out = append(out, Segment{Cmd: 'Q', Args: []float64{x1, y1, cx, cy}})
+		case 'A':
+			cx, cy = args[5], args[6]
+			out = append(out, seg)
+		case 'Z':
+			cx, cy = startX, startY
+			out = append(out, seg)
+		}
+		prev = seg.Cmd
+	}
+	return out
+}
+
+// ToCubic returns an absolute copy of the path that only uses M, L, C and
+// Z: shorthands are expanded, quadratic curves are raised to cubics and
+// elliptical arcs are approximated by cubic segments of at most 90°
+func (p Path) ToCubic() Path {
+	expanded := p.ExpandShorthands()
+	out := make(Path, 0, len(expanded))
+	var cx, cy, startX, startY float64
+
+	for _, seg := range expanded {
+		args := seg.Args
+		switch seg.Cmd {
+		case 'Q':
+			// Degree elevation: control points at 2/3 towards the quad control
+			x1, y1, x, y := args[0], args[1], args[2], args[3]
+			out = append(out, Segment{Cmd: 'C', Args: []float64{
+				cx + 2.0/3*(x1-cx), cy + 2.0/3*(y1-cy),
+				x + 2.0/3*(x1-x), y + 2.0/3*(y1-y),
+				x, y,
+			}})
+			cx, cy = x, y
+		case 'A':
+			out = append(out, arcToCubic(cx, cy, args)...)
+			cx, cy = args[5], args[6]
+		case 'Z':
+			cx, cy = startX, startY
+			out = append(out, seg)
+		default:
+			cx, cy = args[len(args)-2], args[len(args)-1]
+			if seg.Cmd == 'M' {
+				startX, startY = cx, cy
+			}
+			out = append(out, seg)
+		}
+	}
+	return out
+}
+
+// arcToCubic converts an absolute arc from (x1, y1) into cubic segments
+// using the endpoint-to-center conversion from the SVG implementation notes
+func arcToCubic(x1, y1 float64, args []float64) Path {
+	rx, ry, phi := math.Abs(args[0]), math.Abs(args[1]), args[2]*(math.Pi/180)
+	largeArc, sweep := args[3] != 0, args[4] != 0
+	x2, y2 := args[5], args[6]
+
+	if x1 == x2 && y1 == y2 {
+		return nil // a zero-length arc draws nothing
+	}
+	if rx == 0 || ry == 0 {
+		return Path{{Cmd: 'L', Args: []float64{x2, y2}}}
+	}
+
+	cosPhi, sinPhi := math.Cos(phi), math.Sin(phi)
+	dx, dy := (x1-x2)/2, (y1-y2)/2
+	x1p := cosPhi*dx + sinPhi*dy
+	y1p := -sinPhi*dx + cosPhi*dy
+
+	// Scale up radii that are too small to reach the endpoint
+	if lambda := x1p*x1p/(rx*rx) + y1p*y1p/(ry*ry); lambda > 1 {
+		s := math.Sqrt(lambda)
+		rx *= s
+		ry *= s
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1p*y1p - ry*ry*x1p*x1p
+	den := rx*rx*y1p*y1p + ry*ry*x1p*x1p
+	coef := math.Sqrt(math.Max(0, num/den))
+	if largeArc == sweep {
+		coef = -coef
+	}
+	cxp := coef * rx * y1p / ry
+	cyp := -coef * ry * x1p / rx
+	centerX := cosPhi*cxp - sinPhi*cyp + (x1+x2)/2
+	centerY := sinPhi*cxp + cosPhi*cyp + (y1+y2)/2
+
+	theta := vectorAngle(1, 0, (x1p-cxp)/rx, (y1p-cyp)/ry)
+	delta := vectorAngle((x1p-cxp)/rx, (y1p-cyp)/ry, (-x1p-cxp)/rx, (-y1p-cyp)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	// Map a point on the unit circle onto the ellipse
+	toEllipse := func(ux, uy float64) (float64, float64) {
+		return centerX + rx*cosPhi*ux - ry*sinPhi*uy, centerY + rx*sinPhi*ux + ry*cosPhi*uy
+	}
+
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(n)
+	t := 4.0 / 3 * math.Tan(step/4)
+	out := make(Path, 0, n)
+	for i := 0; i < n; i++ {
+		a0 := theta + float64(i)*step
+		a1 := a0 + step
+		cos0, sin0 := math.Cos(a0), math.Sin(a0)
+		cos1, sin1 := math.Cos(a1), math.Sin(a1)
+		c1x, c1y := toEllipse(cos0-t*sin0, sin0+t*cos0)
+		c2x, c2y := toEllipse(cos1+t*sin1, sin1-t*cos1)
+		ex, ey := toEllipse(cos1, sin1)
+		if i == n-1 {
+			ex, ey = x2, y2 // land exactly on the endpoint
+		}
+		out = append(out, Segment{Cmd: 'C', Args: []float64{c1x, c1y, c2x, c2y, ex, ey}})
+	}
+	return out
+}
+
+// vectorAngle returns the signed angle from vector u to vector v
+func vectorAngle(ux, uy, vx, vy float64) float64 {
+	return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+}
@@ -0,0 +1,80 @@
+package pathdata
+
+import "testing"
+
+// mustParse parses path data, failing the test on error
+func mustParse(t *testing.T, d string) Path {
+	t.Helper()
+	p, err := Parse(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestToAbsolute(t *testing.T) {
+	tests := []struct {
+		name, d, want string
+	}{
+		{"relative commands", "m1 1 l2 0 h3 v4 c1 1 2 2 3 3", "M 1 1 L 3 1 H 6 V 5 C 7 6 8 7 9 8"},
+		{"relative arc moves only its endpoint", "M10 10 a5 5 30 0 1 10 0", "M 10 10 A 5 5 30 0 1 20 10"},
+		{"lineto after closepath starts from the subpath start", "M10 10 l5 0 l0 5 z l1 1", "M 10 10 L 15 10 L 15 15 Z L 11 11"},
+		{"moveto after closepath", "m10 10 l5 0 z m2 2 l1 0", "M 10 10 L 15 10 Z M 12 12 L 13 12"},
+		{"implicit relative lineto", "m1 1 2 2 3 3", "M 1 1 L 3 3 L 6 6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustParse(t, tt.d).ToAbsolute().String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandShorthands(t *testing.T) {
+	tests := []struct {
+		name, d, want string
+	}{
+		{"horizontal and vertical lines", "M1 2 H5 V7 h-1 v-1", "M 1 2 L 5 2 L 5 7 L 4 7 L 4 6"},
+		{"repeated horizontal lines", "M0 0 h5 5", "M 0 0 L 5 0 L 10 0"},
+		{"smooth cubic reflects the previous control point", "M0 0 C0 10 10 10 10 0 S20 -10 20 0", "M 0 0 C 0 10 10 10 10 0 C 10 -10 20 -10 20 0"},
+		{"smooth cubic after a line", "M0 0 L10 0 S20 10 30 0", "M 0 0 L 10 0 C 10 0 20 10 30 0"},
+		{"smooth cubic after a quadratic", "M0 0 Q5 10 10 0 S20 10 30 0", "M 0 0 Q 5 10 10 0 C 10 0 20 10 30 0"},
+		{"smooth quadratic chain", "M0 0 Q5 10 10 0 T20 0 T30 0", "M 0 0 Q 5 10 10 0 Q 15 -10 20 0 Q 25 10 30 0"},
+		{"smooth quadratic after a line", "M0 0 L10 0 T20 0", "M 0 0 L 10 0 Q 10 0 20 0"},
+		{"smooth quadratic after a cubic", "M0 0 C0 10 10 10 10 0 T20 0", "M 0 0 C 0 10 10 10 10 0 Q 10 0 20 0"},
+		{"smooth cubic after closepath", "M0 0 C0 10 10 10 10 0 Z S5 5 10 10", "M 0 0 C 0 10 10 10 10 0 Z C 0 0 5 5 10 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustParse(t, tt.d).ExpandShorthands().String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToCubic(t *testing.T) {
+	tests := []struct {
+		name, d, want string
+	}{
+		{"quadratic raised to cubic", "M0 0 Q3 3 6 0", "M 0 0 C 2 2 4 2 6 0"},
+		{"smooth quadratic after a line", "M0 0 L3 0 T9 0", "M 0 0 L 3 0 C 3 0 5 0 9 0"},
+		{"quarter arc", "M10 0 A10 10 0 0 1 0 10", "M 10 0 C 10 5.5228 5.5228 10 0 10"},
+		{"counter-clockwise quarter arc", "M10 0 A10 10 0 0 0 0 10", "M 10 0 C 4.4772 0 0 4.4772 0 10"},
+		// radii of 1 cannot span 10 units and are scaled up to 5
+		{"radii scaled up", "M0 0 A1 1 0 0 1 10 0", "M 0 0 C 0 -2.7614 2.2386 -5 5 -5 C 7.7614 -5 10 -2.7614 10 0"},
+		// 270° around (10,0), one cubic per quarter turn
+		{"large arc split into quarters", "M0 0 A10 10 0 1 1 10 10", "M 0 0 C 0 -5.5228 4.4772 -10 10 -10 C 15.5228 -10 20 -5.5228 20 0 C 20 5.5228 15.5228 10 10 10"},
+		{"zero radius arc becomes a line", "M0 0 A0 5 0 0 1 10 10", "M 0 0 L 10 10"},
+		{"zero length arc is dropped", "M5 5 A5 5 0 0 1 5 5 L6 6", "M 5 5 L 6 6"},
+		{"relative arc after closepath", "M10 0 L20 0 Z a10 10 0 0 1 -10 10", "M 10 0 L 20 0 Z C 10 5.5228 5.5228 10 0 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustParse(t, tt.d).ToCubic().Format(4); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package pathdata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Segment is a single path command with its arguments. Cmd is the SVG
+// command letter; lowercase letters are relative to the current point.
+type Segment struct {
+	Cmd  byte
+	Args []float64
+}
+
+// Path is a parsed SVG path: an ordered list of segments
+type Path []Segment
+
+// SyntaxError reports malformed path data at a byte offset
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("path data offset %d: %s", e.Offset, e.Msg)
+}
+
+// arity is the number of arguments each command consumes
+var arity = map[byte]int{
+	'M': 2, 'L': 2, 'H': 1, 'V': 1, 'C': 6, 'S': 4, 'Q': 4, 'T': 2, 'A': 7, 'Z': 0,
+}
+
+// Arity returns the number of arguments taken by a command letter, or -1
+// if the letter is not a path command
+func Arity(cmd byte) int {
+	n, ok := arity[upper(cmd)]
+	if !ok {
+		return -1
+	}
+	return n
+}
+
+// IsRelative reports whether a command letter is relative (lowercase)
+func IsRelative(cmd byte) bool {
+	return cmd >= 'a' && cmd <= 'z'
+}
+
+func upper(cmd byte) byte {
+	if IsRelative(cmd) {
+		return cmd - 'a' + 'A'
+	}
+	return cmd
+}
+
+// Parse parses SVG path data ("d" attribute syntax). Implicitly repeated
+// argument groups become separate segments; extra pairs after a moveto
+// become lineto segments, as the SVG grammar specifies.
+func Parse(d string) (Path, error) {
+	s := &scanner{src: d}
+	var path Path
+	for {
+		s.skipSeparators()
+		if s.done() {
+			return path, nil
+		}
+		off := s.off
+		cmd := s.src[s.off]
+		n := Arity(cmd)
+		if n < 0 {
+			return nil, &SyntaxError{Offset: off, Msg: fmt.Sprintf("unexpected %q, expected a command", cmd)}
+		}
+		if len(path) == 0 && upper(cmd) != 'M' {
+			return nil, &SyntaxError{Offset: off, Msg: "path data must start with a moveto"}
+		}
+		s.off++
+
+		if n == 0 {
+			path = append(path, Segment{Cmd: cmd})
+			continue
+		}
+
+		for first := true; ; first = false {
+			s.skipSeparators()
+			if !first && !s.startsNumber() {
+				break
+			}
+			args := make([]float64, n)
+			for i := range args {
+				var err error
+				if upper(cmd) == 'A' && (i == 3 || i == 4) {
+					args[i], err = s.flag()
+				} else {
+					args[i], err = s.number()
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			path = append(path, Segment{Cmd: cmd, Args: args})
+
+			// Subsequent pairs after a moveto are implicit linetos
+			if cmd == 'M' {
+				cmd = 'L'
+			} else if cmd == 'm' {
+				cmd = 'l'
+			}
+		}
+	}
+}
+
+// String serializes the path using the shortest exact number formatting
+func (p Path) String() string {
+	return p.Format(-1)
+}
+
+// Format serializes the path with numbers rounded to the given number of
+// decimal places; a negative precision keeps full precision
+func (p Path) Format(precision int) string {
+	var b strings.Builder
+	for i, seg := range p {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteByte(seg.Cmd)
+		for _, v := range seg.Args {
+			b.WriteByte(' ')
+			b.WriteString(formatNumber(v, precision))
+		}
+	}
+	return b.String()
+}
+
+func formatNumber(v float64, precision int) string {
+	s := strconv.FormatFloat(v, 'f', precision, 64)
+	if precision > 0 {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// scanner reads numbers and flags from path data
+type scanner struct {
+	src string
+	off int
+}
+
+func (s *scanner) done() bool {
+	return s.off >= len(s.src)
+}
+
+func (s *scanner) skipSeparators() {
+	for !s.done() && strings.IndexByte(" \t\r\n\f,", s.src[s.off]) != -1 {
+		s.off++
+	}
+}
+
+func (s *scanner) startsNumber() bool {
+	if s.done() {
+		return false
+	}
+	c := s.src[s.off]
+	return isDigit(c) || c == '.' || c == '-' || c == '+'
+}
+
+// number reads one number; a sign or second decimal point ends it
+func (s *scanner) number() (float64, error) {
+	s.skipSeparators()
+	start := s.off
+	if !s.done() && (s.src[s.off] == '+' || s.src[s.off] == '-') {
+		s.off++
+	}
+	digits := 0
+	for !s.done() && isDigit(s.src[s.off]) {
+		s.off++
+		digits++
+	}
+	if !s.done() && s.src[s.off] == '.' {
+		s.off++
+		for !s.done() && isDigit(s.src[s.off]) {
+			s.off++
+			digits++
+		}
+	}
+	if digits == 0 {
+		s.off = start
+		return 0, s.expected("number")
+	}
+	if !s.done() && (s.src[s.off] == 'e' || s.src[s.off] == 'E') {
+		save := s.off
+		s.off++
+		if !s.done() && (s.src[s.off] == '+' || s.src[s.off] == '-') {
+			s.off++
+		}
+		if s.done() || !isDigit(s.src[s.off]) {
+			s.off = save
+		}
+		for !s.done() && isDigit(s.src[s.off]) {
+			s.off++
+		}
+	}
+	v, err := strconv.ParseFloat(s.src[start:s.off], 64)
+	if err != nil {
+		return 0, &SyntaxError{Offset: start, Msg: fmt.Sprintf("invalid number %q", s.src[start:s.off])}
+	}
+	return v, nil
+}
+
+// flag reads a single-character arc flag, which may be written without
+// separators (e.g. "a10 10 0 0110 10")
+func (s *scanner) flag() (float64, error) {
+	s.skipSeparators()
+	if s.done() || (s.src[s.off] != '0' && s.src[s.off] != '1') {
+		return 0, s.expected("arc flag 0 or 1")
+	}
+	v := float64(s.src[s.off] - '0')
+	s.off++
+	return v, nil
+}
+
+func (s *scanner) expected(what string) error {
+	if s.done() {
+		return &SyntaxError{Offset: s.off, Msg: fmt.Sprintf("expected %s, found end of data", what)}
+	}
+	return &SyntaxError{Offset: s.off, Msg: fmt.Sprintf("expected %s, found %q", what, s.src[s.off])}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
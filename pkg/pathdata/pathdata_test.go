@@ -0,0 +1,54 @@
+package pathdata
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, d, want string
+	}{
+		{"implicit lineto after moveto", "M0 0 10 0 10 10", "M 0 0 L 10 0 L 10 10"},
+		{"implicit relative lineto after moveto", "m1 1 2 2", "m 1 1 l 2 2"},
+		{"implicit repeated command", "M0 0 L1 1 2 2 h3 4", "M 0 0 L 1 1 L 2 2 h 3 h 4"},
+		{"repeated curve", "M0 0 c1 1 2 2 3 3 4 4 5 5 6 6", "M 0 0 c 1 1 2 2 3 3 c 4 4 5 5 6 6"},
+		{"compact numbers", "M.5.5-1-1L1e2-2", "M 0.5 0.5 L -1 -1 L 100 -2"},
+		{"compact arc flags", "M0 0 a1 1 0 00 1 1", "M 0 0 a 1 1 0 0 0 1 1"},
+		{"arc flags joined to the endpoint", "M0 0 a10 10 0 0110 10", "M 0 0 a 10 10 0 0 1 10 10"},
+		{"closepath then moveto", "M0 0L1 0Zm2 2z", "M 0 0 L 1 0 Z m 2 2 z"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse(tt.d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %q, want %q", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		d      string
+		offset int
+	}{
+		{"L0 0", 0},
+		{"M0", 2},
+		{"M0 0 X", 5},
+		{"M0 0 A1 1 0 2 0 1 1", 12},
+		{"M0 0 L1 -", 8},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.d)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Parse(%q) error = %v, want a SyntaxError", tt.d, err)
+			continue
+		}
+		if serr.Offset != tt.offset {
+			t.Errorf("Parse(%q) error at offset %d, want %d: %v", tt.d, serr.Offset, tt.offset, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package pathdata
+
+import (
+	"math"
+
+	"github.com/prabinpanta0/VectorFormatBridge/pkg/transform"
+)
+
+// ToAbsolute returns a copy of the path with every relative command
+// converted to its absolute form
+func (p Path) ToAbsolute() Path {
+	out := make(Path, 0, len(p))
+	var cx, cy, startX, startY float64
+	for _, seg := range p {
+		cmd := upper(seg.Cmd)
+		args := append([]float64(nil), seg.Args...)
+		if IsRelative(seg.Cmd) {
+			switch cmd {
+			case 'H':
+				args[0] += cx
+			case 'V':
+				args[0] += cy
+			case 'A':
+				args[5] += cx
+				args[6] += cy
+			default:
+				for i := 0; i+1 < len(args); i += 2 {
+					args[i] += cx
+					args[i+1] += cy
+				}
+			}
+		}
+
+		switch cmd {
+		case 'Z':
+			cx, cy = startX, startY
+		case 'H':
+			cx = args[0]
+		case 'V':
+			cy = args[0]
+		default:
+			cx, cy = args[len(args)-2], args[len(args)-1]
+		}
+		if cmd == 'M' {
+			startX, startY = cx, cy
+		}
+		out = append(out, Segment{Cmd: cmd, Args: args})
+	}
+	return out
+}
+
+// Transform returns the path with the affine matrix applied to every
+// segment. The result uses absolute commands. Horizontal and vertical
+// lines become general lines when m rotates or skews, and elliptical arcs
+// get new radii, rotation and sweep so they trace the transformed curve.
+func (p Path) Transform(m transform.Matrix) Path {
+	abs := p.ToAbsolute()
+	out := make(Path, 0, len(abs))
+	axisAligned := m.IsAxisAligned()
+	var cx, cy, startX, startY float64
+
+	for _, seg := range abs {
+		args := seg.Args
+		next := make([]float64, len(args))
+		cmd := seg.Cmd
+
+		switch cmd {
+		case 'Z':
+			cx, cy = startX, startY
+			out = append(out, Segment{Cmd: 'Z'})
+			continue
+
+		case 'H':
+			if axisAligned {
+				next[0] = m.A*args[0] + m.E
+			} else {
+				cmd = 'L'
+				next = make([]float64, 2)
+				next[0], next[1] = m.Apply(args[0], cy)
+			}
+			cx = args[0]
+
+		case 'V':
+			if axisAligned {
+				next[0] = m.D*args[0] + m.F
+			} else {
+				cmd = 'L'
+				next = make([]float64, 2)
+				next[0], next[1] = m.Apply(cx, args[0])
+			}
+			cy = args[0]
+
+		case 'A':
+			rx, ry, phi := transformArc(m, args[0], args[1], args[2])
+			next[0], next[1], next[2] = rx, ry, phi
+			next[3] = args[3]
+			next[4] = args[4]
+			if m.Determinant() < 0 {
+				next[4] = 1 - args[4] // reflection reverses direction
+			}
+			next[5], next[6] = m.Apply(args[5], args[6])
+			cx, cy = args[5], args[6]
+
+		default:
+			// M, L, C, S, Q, T: every argument pair is a point. Reflected
+			// control points of S and T stay valid because affine maps
+			// preserve midpoints.
+			for i := 0; i+1 < len(args); i += 2 {
+				next[i], next[i+1] = m.Apply(args[i], args[i+1])
+			}
+			cx, cy = args[len(args)-2], args[len(args)-1]
+		}
+
+		if cmd == 'M' {
+			startX, startY = cx, cy
+		}
+		out = append(out, Segment{Cmd: cmd, Args: next})
+	}
+	return out
+}
+
+// transformArc maps the ellipse with radii rx, ry rotated by phi degrees
+// through the linear part of m and returns the new radii and rotation
+func transformArc(m transform.Matrix, rx, ry, phi float64) (float64, float64, float64) {
+	rad := phi * (math.Pi / 180)
+	cos, sin := math.Cos(rad), math.Sin(rad)
+
+	// Columns of the matrix mapping the unit circle onto the new ellipse
+	p, q := m.ApplyVector(rx*cos, rx*sin)
+	r, s := m.ApplyVector(-ry*sin, ry*cos)
+
+	// The semi-axes are the square roots of the eigenvalues of N·Nᵀ
+	a11 := p*p + r*r
+	a12 := p*q + r*s
+	a22 := q*q + s*s
+	mid := (a11 + a22) / 2
+	d := math.Hypot((a11-a22)/2, a12)
+	newRx := math.Sqrt(mid + d)
+	newRy := math.Sqrt(math.Max(mid-d, 0))
+	angle := 0.5 * math.Atan2(2*a12, a11-a22) * (180 / math.Pi)
+	return newRx, newRy, angle
+}
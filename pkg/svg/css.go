@@ -0,0 +1,354 @@
+package svg
+
+import (
+	"sort"
+	"strings"
+)
+
+// Rule is a CSS rule set: declarations applied to elements matching any
+// of its selectors
+type Rule struct {
+	Selectors    []*Selector
+	Declarations []Declaration
+}
+
+// Selector is a chain of compound selectors joined by descendant
+// combinators, such as "g.layer rect"
+type Selector struct {
+	// parts holds the compound selectors, the subject last
+	parts []compound
+}
+
+// compound is a simple selector sequence such as rect.cls-1#logo
+type compound struct {
+	tag     string // "" or "*" match any element
+	id      string
+	classes []string
+}
+
+// ParseStylesheet parses the rule sets of a <style> element. Selectors are
+// limited to type, class, id and universal selectors combined by
+// descendant combinators and grouped with commas. As in CSS, a rule whose
+// selector list contains anything else is dropped, and at-rules such as
+// @media are skipped.
+func ParseStylesheet(s string) []Rule {
+	s = stripComments(s)
+	s = strings.NewReplacer("<!--", " ", "-->", " ").Replace(s)
+
+	var rules []Rule
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return rules
+		}
+		if s[0] == '@' {
+			s = skipAtRule(s)
+			continue
+		}
+		open := strings.IndexByte(s, '{')
+		if open == -1 {
+			return rules
+		}
+		end := blockEnd(s, open)
+		prelude, body := s[:open], s[open+1:end]
+		if end < len(s) {
+			end++
+		}
+		s = s[end:]
+
+		selectors, ok := parseSelectorList(prelude)
+		if !ok {
+			continue
+		}
+		rules = append(rules, Rule{Selectors: selectors, Declarations: ParseDeclarations(body)})
+	}
+}
+
+// skipAtRule skips an at-rule: up to its ';', or past its block if the
+// block comes first
+func skipAtRule(s string) string {
+	semi := strings.IndexByte(s, ';')
+	open := strings.IndexByte(s, '{')
+	if open == -1 || (semi != -1 && semi < open) {
+		if semi == -1 {
+			return ""
+		}
+		return s[semi+1:]
+	}
+	end := blockEnd(s, open)
+	if end < len(s) {
+		end++
+	}
+	return s[end:]
+}
+
+// blockEnd returns the index of the '}' closing the block opened at open,
+// or len(s) if the block is unterminated
+func blockEnd(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// parseSelectorList parses comma-separated selectors, reporting false if
+// any is malformed or unsupported
+func parseSelectorList(s string) ([]*Selector, bool) {
+	var selectors []*Selector
+	for _, text := range strings.Split(s, ",") {
+		sel, ok := parseSelector(text)
+		if !ok {
+			return nil, false
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors, true
+}
+
+func parseSelector(s string) (*Selector, bool) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	sel := &Selector{}
+	for _, field := range fields {
+		c, ok := parseCompound(field)
+		if !ok {
+			return nil, false
+		}
+		sel.parts = append(sel.parts, c)
+	}
+	return sel, true
+}
+
+// parseCompound parses a type or universal selector followed by any
+// number of #id and .class selectors
+func parseCompound(s string) (compound, bool) {
+	var c compound
+	name := func(start int) int {
+		i := start
+		for i < len(s) && isNameChar(s[i]) {
+			i++
+		}
+		return i
+	}
+
+	i := 0
+	if s[0] == '*' {
+		c.tag = "*"
+		i = 1
+	} else if end := name(0); end > 0 {
+		c.tag = strings.ToLower(s[:end])
+		i = end
+	}
+	for i < len(s) {
+		kind := s[i]
+		if kind != '#' && kind != '.' {
+			return c, false
+		}
+		end := name(i + 1)
+		if end == i+1 {
+			return c, false
+		}
+		if kind == '#' {
+			c.id = s[i+1 : end]
+		} else {
+			c.classes = append(c.classes, s[i+1:end])
+		}
+		i = end
+	}
+	return c, true
+}
+
+func isNameChar(c byte) bool {
+	return c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// specificity returns the selector's (ids, classes, types) weight packed
+// into one comparable number
+func (sel *Selector) specificity() int {
+	ids, classes, types := 0, 0, 0
+	for _, c := range sel.parts {
+		if c.id != "" {
+			ids++
+		}
+		classes += len(c.classes)
+		if c.tag != "" && c.tag != "*" {
+			types++
+		}
+	}
+	return ids<<16 | classes<<8 | types
+}
+
+// scope identifies an element for selector matching
+type scope struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+func newScope(tag string, a *Attrs) scope {
+	return scope{tag: tag, id: a.ID, classes: strings.Fields(a.Class)}
+}
+
+func (c compound) matches(s scope) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != s.tag {
+		return false
+	}
+	if c.id != "" && c.id != s.id {
+		return false
+	}
+	for _, want := range c.classes {
+		found := false
+		for _, class := range s.classes {
+			if class == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the selector matches the last element of path,
+// which lists the element's ancestors from the root down
+func (sel *Selector) matches(path []scope) bool {
+	last := len(sel.parts) - 1
+	if !sel.parts[last].matches(path[len(path)-1]) {
+		return false
+	}
+	// Match the remaining parts against ancestors, nearest first; taking
+	// the nearest matching ancestor each time is enough for descendant
+	// combinators
+	j := len(path) - 2
+	for i := last - 1; i >= 0; i-- {
+		for j >= 0 && !sel.parts[i].matches(path[j]) {
+			j--
+		}
+		if j < 0 {
+			return false
+		}
+		j--
+	}
+	return true
+}
+
+// cascade applies the document's stylesheets to every element, storing
+// the matching declarations in the order they take effect
+func (s *SVG) cascade() {
+	var rules []Rule
+	collectStylesheets(s.Children, &rules)
+	if len(rules) == 0 {
+		return
+	}
+	path := []scope{newScope("svg", &s.Attrs)}
+	s.sheet = matchRules(rules, path)
+	applyRules(s.Children, rules, path)
+}
+
+func collectStylesheets(els []Element, rules *[]Rule) {
+	for _, el := range els {
+		switch el := el.(type) {
+		case *StyleSheet:
+			*rules = append(*rules, ParseStylesheet(el.Text)...)
+		case *Group:
+			collectStylesheets(el.Children, rules)
+		}
+	}
+}
+
+func applyRules(els []Element, rules []Rule, path []scope) {
+	for _, el := range els {
+		tag := tagName(el)
+		if tag == "" {
+			continue
+		}
+		a := el.Common()
+		path := append(path[:len(path):len(path)], newScope(tag, a))
+		a.sheet = matchRules(rules, path)
+
+		if g, ok := el.(*Group); ok {
+			applyRules(g.Children, rules, path)
+		}
+	}
+}
+
+// matchRules returns the declarations of the rules matching the last
+// element of path, ordered by specificity and then source order
+func matchRules(rules []Rule, path []scope) []Declaration {
+	type match struct {
+		specificity, order int
+		decl               Declaration
+	}
+	var matched []match
+	for i, rule := range rules {
+		best := -1
+		for _, sel := range rule.Selectors {
+			if sp := sel.specificity(); sp > best && sel.matches(path) {
+				best = sp
+			}
+		}
+		if best == -1 {
+			continue
+		}
+		for _, d := range rule.Declarations {
+			matched = append(matched, match{specificity: best, order: i, decl: d})
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].specificity != matched[j].specificity {
+			return matched[i].specificity < matched[j].specificity
+		}
+		return matched[i].order < matched[j].order
+	})
+	decls := make([]Declaration, len(matched))
+	for i, m := range matched {
+		decls[i] = m.decl
+	}
+	return decls
+}
+
+// tagName returns the element name of a rendered element, or "" for
+// elements such as <style> that selectors do not apply to
+func tagName(el Element) string {
+	switch el.(type) {
+	case *Group:
+		return "g"
+	case *Rect:
+		return "rect"
+	case *Circle:
+		return "circle"
+	case *Line:
+		return "line"
+	case *Path:
+		return "path"
+	case *Ellipse:
+		return "ellipse"
+	case *Polygon:
+		return "polygon"
+	case *Polyline:
+		return "polyline"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,163 @@
+package svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// UnmarshalXML decodes the root element, keeping children in document order
+// so that paint order (z-order) survives conversion
+func (s *SVG) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if start.Name.Local != "svg" {
+		return fmt.Errorf("expected <svg> root element, found <%s>", start.Name.Local)
+	}
+	s.XMLName = start.Name
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "width":
+			s.Width = attr.Value
+		case "height":
+			s.Height = attr.Value
+		case "viewBox":
+			s.ViewBox = attr.Value
+		default:
+			s.setAttr(attr)
+		}
+	}
+
+	children, err := decodeChildren(d)
+	if err != nil {
+		return err
+	}
+	s.Children = children
+	s.cascade()
+	return nil
+}
+
+// UnmarshalXML decodes a <g> element and its nested children
+func (g *Group) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		g.setAttr(attr)
+	}
+
+	children, err := decodeChildren(d)
+	if err != nil {
+		return err
+	}
+	g.Children = children
+	return nil
+}
+
+// setAttr stores an attribute of an element decoded by hand the way
+// encoding/xml fills the tagged Attrs fields of the others: the shared
+// attributes by local name, and any other attribute in Other
+func (a *Attrs) setAttr(attr xml.Attr) {
+	switch attr.Name.Local {
+	case "id":
+		a.ID = attr.Value
+	case "class":
+		a.Class = attr.Value
+	case "fill":
+		a.Fill = attr.Value
+	case "stroke":
+		a.Stroke = attr.Value
+	case "style":
+		a.Style = attr.Value
+	case "transform":
+		a.Transform = attr.Value
+	default:
+		a.Other = append(a.Other, attr)
+	}
+}
+
+// UnmarshalXML decodes a <style> element, keeping its text and CDATA
+func (s *StyleSheet) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			s.Text = text.String()
+			return nil
+		}
+	}
+}
+
+// decodeChildren reads child elements up to the parent's end tag.
+// Unsupported elements are skipped along with their content.
+func decodeChildren(d *xml.Decoder) ([]Element, error) {
+	var children []Element
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "defs" {
+				// Definitions are not drawn, but their stylesheets apply
+				defs, err := decodeChildren(d)
+				if err != nil {
+					return nil, err
+				}
+				for _, el := range defs {
+					if sheet, ok := el.(*StyleSheet); ok {
+						children = append(children, sheet)
+					}
+				}
+				continue
+			}
+			el := newElement(t.Name.Local)
+			if el == nil {
+				if err := d.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := d.DecodeElement(el, &t); err != nil {
+				return nil, err
+			}
+			children = append(children, el)
+
+		case xml.EndElement:
+			return children, nil
+		}
+	}
+}
+
+// newElement allocates the element type for a tag name, or nil if unsupported
+func newElement(name string) Element {
+	switch name {
+	case "g":
+		return &Group{}
+	case "rect":
+		return &Rect{}
+	case "circle":
+		return &Circle{}
+	case "line":
+		return &Line{}
+	case "path":
+		return &Path{}
+	case "ellipse":
+		return &Ellipse{}
+	case "polygon":
+		return &Polygon{}
+	case "polyline":
+		return &Polyline{}
+	case "style":
+		return &StyleSheet{}
+	default:
+		return nil
+	}
+}
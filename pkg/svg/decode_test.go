@@ -0,0 +1,45 @@
+package svg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestAttributesDecodedAlike checks that the root and groups, which are
+// decoded by hand, keep the same attributes as the other elements
+func TestAttributesDecodedAlike(t *testing.T) {
+	const attrs = `id="a" class="c" fill="red" stroke="blue" style="opacity:1" transform="scale(2)" stroke-width="3" xlink:title="t"`
+	doc, err := Decode(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" ` + attrs + `>
+  <g ` + attrs + `/>
+  <rect ` + attrs + `/>
+</svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Children) != 2 {
+		t.Fatalf("got %d children, want 2", len(doc.Children))
+	}
+
+	rect := doc.Children[1].Common()
+	if got := rect.Property("stroke-width"); got != "3" {
+		t.Errorf("rect stroke-width = %q, want 3", got)
+	}
+	for name, a := range map[string]*Attrs{"svg": &doc.Attrs, "g": doc.Children[0].Common()} {
+		var other []string
+		for _, attr := range a.Other {
+			if attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" {
+				other = append(other, attr.Name.Local)
+			}
+		}
+		if want := []string{"stroke-width", "title"}; !reflect.DeepEqual(other, want) {
+			t.Errorf("%s keeps other attributes %v, want %v", name, other, want)
+		}
+		if a.ID != rect.ID || a.Class != rect.Class || a.Fill != rect.Fill || a.Stroke != rect.Stroke || a.Style != rect.Style || a.Transform != rect.Transform {
+			t.Errorf("%s attributes %+v differ from rect %+v", name, a, rect)
+		}
+		if got := a.Property("stroke-width"); got != "3" {
+			t.Errorf("%s stroke-width = %q, want 3", name, got)
+		}
+	}
+}
@@ -1,24 +1,38 @@
 package svg
 
 import (
+	"bytes"
 	"encoding/xml"
+	"io"
 	"io/ioutil"
+	"os"
 )
 
-// ParseSVG reads and parses an SVG file
-func ParseSVG(filename string) (*SVG, error) {
-	data, err := ioutil.ReadFile(filename)
+// Decode reads and parses an SVG document from r
+func Decode(r io.Reader) (*SVG, error) {
+	var svg SVG
+	err := xml.NewDecoder(r).Decode(&svg)
 	if err != nil {
 		return nil, err
 	}
 
-	var svg SVG
-	err = xml.Unmarshal(data, &svg)
+	return &svg, nil
+}
+
+// Unmarshal parses an SVG document held in memory
+func Unmarshal(data []byte) (*SVG, error) {
+	return Decode(bytes.NewReader(data))
+}
+
+// ParseSVG reads and parses an SVG file
+func ParseSVG(filename string) (*SVG, error) {
+	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
-	return &svg, nil
+	return Decode(f)
 }
 
 // WriteSVG writes SVG content to a file
@@ -0,0 +1,132 @@
+package svg
+
+import "strings"
+
+// Declaration is one property: value pair of a CSS declaration list
+type Declaration struct {
+	Property  string
+	Value     string
+	Important bool
+}
+
+// ParseDeclarations parses a CSS declaration list such as the value of a
+// style attribute. Property names are lowercased. Malformed declarations are
+// skipped, as browsers do, so one typo does not lose the rest of the style.
+func ParseDeclarations(s string) []Declaration {
+	var decls []Declaration
+	for _, part := range splitTopLevel(stripComments(s), ';') {
+		colon := strings.IndexByte(part, ':')
+		if colon == -1 {
+			continue
+		}
+		prop := strings.ToLower(strings.TrimSpace(part[:colon]))
+		if prop == "" || strings.ContainsAny(prop, " \t\r\n") {
+			continue
+		}
+		value, important := cutImportant(strings.TrimSpace(part[colon+1:]))
+		if value == "" {
+			continue
+		}
+		decls = append(decls, Declaration{Property: prop, Value: value, Important: important})
+	}
+	return decls
+}
+
+// cutImportant removes a trailing !important from a value
+func cutImportant(value string) (string, bool) {
+	bang := strings.LastIndexByte(value, '!')
+	if bang == -1 || !strings.EqualFold(strings.TrimSpace(value[bang+1:]), "important") {
+		return value, false
+	}
+	return strings.TrimSpace(value[:bang]), true
+}
+
+// stripComments removes /* ... */ comments outside of quoted strings
+func stripComments(s string) string {
+	if !strings.Contains(s, "/*") {
+		return s
+	}
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			end := strings.Index(s[i+2:], "*/")
+			if end == -1 {
+				return b.String()
+			}
+			i += end + 3
+			b.WriteByte(' ')
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// splitTopLevel splits s at sep, ignoring separators inside quotes and
+// parentheses so values like url(data:image/png;base64,...) stay whole
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// Property returns the value an element specifies for a presentation
+// property, or "" if it sets none. Following the CSS cascade, the style
+// attribute overrides stylesheet rules, which override the presentation
+// attribute of the same name; an !important declaration overrides any
+// normal one, and otherwise the last declaration wins.
+func (a *Attrs) Property(name string) string {
+	value, important := "", false
+	consider := func(decls []Declaration) {
+		for _, d := range decls {
+			if d.Property == name && (d.Important || !important) {
+				value, important = d.Value, d.Important
+			}
+		}
+	}
+	consider(a.sheet)
+	consider(ParseDeclarations(a.Style))
+	if value != "" {
+		return value
+	}
+	switch name {
+	case "fill":
+		return a.Fill
+	case "stroke":
+		return a.Stroke
+	}
+	for _, attr := range a.Other {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return strings.TrimSpace(attr.Value)
+		}
+	}
+	return ""
+}
@@ -4,72 +4,107 @@ import "encoding/xml"
 
 // SVG represents the root SVG element and its child elements
 type SVG struct {
-	XMLName   xml.Name   `xml:"svg"`
-	Width     string     `xml:"width,attr"`
-	Height    string     `xml:"height,attr"`
-	Rects     []Rect     `xml:"rect"`
-	Circles   []Circle   `xml:"circle"`
-	Lines     []Line     `xml:"line"`
-	Paths     []Path     `xml:"path"`
-	Ellipses  []Ellipse  `xml:"ellipse"`
-	Polygons  []Polygon  `xml:"polygon"`
-	Polylines []Polyline `xml:"polyline"`
+	XMLName xml.Name
+	Width   string
+	Height  string
+	ViewBox string
+	// Attrs holds the root's own presentation attributes, which every
+	// element inherits
+	Attrs
+	// Children holds the supported child elements in document (paint) order
+	Children []Element
+}
+
+// Element is a supported SVG child element: *Group, *Rect, *Circle, *Line,
+// *Path, *Ellipse, *Polygon, *Polyline, or a *StyleSheet, which draws nothing
+type Element interface {
+	Common() *Attrs
+}
+
+// Attrs holds the attributes shared by every supported element. Use
+// Property to read a presentation property, which also honors Style and
+// the document's stylesheets.
+type Attrs struct {
+	ID        string `xml:"id,attr"`
+	Class     string `xml:"class,attr"`
+	Fill      string `xml:"fill,attr"`
+	Stroke    string `xml:"stroke,attr"`
+	Style     string `xml:"style,attr"`
+	Transform string `xml:"transform,attr"`
+	// Other holds the remaining attributes, including presentation
+	// attributes such as stroke-width
+	Other []xml.Attr `xml:",any,attr"`
+
+	// sheet holds the stylesheet declarations matching the element, in
+	// cascade order
+	sheet []Declaration
+}
+
+// Common returns the shared attributes of an element
+func (a *Attrs) Common() *Attrs { return a }
+
+// Group represents an SVG <g> element. Its presentation attributes are
+// inherited by descendants that do not set their own.
+type Group struct {
+	Attrs
+	Children []Element
+}
+
+// StyleSheet represents an SVG <style> element holding CSS rules
+type StyleSheet struct {
+	Attrs
+	Text string
 }
 
 // Rect represents an SVG rectangle element
 type Rect struct {
+	Attrs
 	X      string `xml:"x,attr"`
 	Y      string `xml:"y,attr"`
 	Width  string `xml:"width,attr"`
 	Height string `xml:"height,attr"`
-	Fill   string `xml:"fill,attr"`
-	Stroke string `xml:"stroke,attr"`
 }
 
 // Circle represents an SVG circle element
 type Circle struct {
-	Cx     string `xml:"cx,attr"`
-	Cy     string `xml:"cy,attr"`
-	R      string `xml:"r,attr"`
-	Fill   string `xml:"fill,attr"`
-	Stroke string `xml:"stroke,attr"`
+	Attrs
+	Cx string `xml:"cx,attr"`
+	Cy string `xml:"cy,attr"`
+	R  string `xml:"r,attr"`
 }
 
 // Line represents an SVG line element
 type Line struct {
-	X1     string `xml:"x1,attr"`
-	Y1     string `xml:"y1,attr"`
-	X2     string `xml:"x2,attr"`
-	Y2     string `xml:"y2,attr"`
-	Stroke string `xml:"stroke,attr"`
+	Attrs
+	X1 string `xml:"x1,attr"`
+	Y1 string `xml:"y1,attr"`
+	X2 string `xml:"x2,attr"`
+	Y2 string `xml:"y2,attr"`
 }
 
 // Path represents an SVG path element
 type Path struct {
-	D      string `xml:"d,attr"`
-	Stroke string `xml:"stroke,attr"`
-	Fill   string `xml:"fill,attr"`
+	Attrs
+	D string `xml:"d,attr"`
 }
 
 // Ellipse represents an SVG ellipse element
 type Ellipse struct {
-	Cx     string `xml:"cx,attr"`
-	Cy     string `xml:"cy,attr"`
-	Rx     string `xml:"rx,attr"`
-	Ry     string `xml:"ry,attr"`
-	Fill   string `xml:"fill,attr"`
-	Stroke string `xml:"stroke,attr"`
+	Attrs
+	Cx string `xml:"cx,attr"`
+	Cy string `xml:"cy,attr"`
+	Rx string `xml:"rx,attr"`
+	Ry string `xml:"ry,attr"`
 }
 
 // Polygon represents an SVG polygon element
 type Polygon struct {
+	Attrs
 	Points string `xml:"points,attr"`
-	Fill   string `xml:"fill,attr"`
-	Stroke string `xml:"stroke,attr"`
 }
 
 // Polyline represents an SVG polyline element
 type Polyline struct {
+	Attrs
 	Points string `xml:"points,attr"`
-	Stroke string `xml:"stroke,attr"`
 }
@@ -0,0 +1,248 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrSingular is returned when inverting a matrix with zero determinant
+var ErrSingular = errors.New("matrix is not invertible")
+
+// epsilon is the tolerance used when classifying matrices
+const epsilon = 1e-9
+
+// Matrix is a 2D affine transform using the SVG matrix(a,b,c,d,e,f) layout:
+//
+//	| A C E |
+//	| B D F |
+//	| 0 0 1 |
+type Matrix struct {
+	A, B, C, D, E, F float64
+}
+
+// Identity returns the identity matrix
+func Identity() Matrix {
+	return Matrix{A: 1, D: 1}
+}
+
+// Translate returns a translation matrix
+func Translate(tx, ty float64) Matrix {
+	return Matrix{A: 1, D: 1, E: tx, F: ty}
+}
+
+// Scale returns a (possibly non-uniform) scaling matrix
+func Scale(sx, sy float64) Matrix {
+	return Matrix{A: sx, D: sy}
+}
+
+// Rotate returns a rotation matrix for an angle in degrees
+func Rotate(deg float64) Matrix {
+	rad := deg * (math.Pi / 180)
+	cos, sin := math.Cos(rad), math.Sin(rad)
+	return Matrix{A: cos, B: sin, C: -sin, D: cos}
+}
+
+// SkewX returns a horizontal skew matrix for an angle in degrees
+func SkewX(deg float64) Matrix {
+	return Matrix{A: 1, C: math.Tan(deg * (math.Pi / 180)), D: 1}
+}
+
+// SkewY returns a vertical skew matrix for an angle in degrees
+func SkewY(deg float64) Matrix {
+	return Matrix{A: 1, B: math.Tan(deg * (math.Pi / 180)), D: 1}
+}
+
+// Multiply returns m·n, the transform that applies n first and then m
+func (m Matrix) Multiply(n Matrix) Matrix {
+	return Matrix{
+		A: m.A*n.A + m.C*n.B,
+		B: m.B*n.A + m.D*n.B,
+		C: m.A*n.C + m.C*n.D,
+		D: m.B*n.C + m.D*n.D,
+		E: m.A*n.E + m.C*n.F + m.E,
+		F: m.B*n.E + m.D*n.F + m.F,
+	}
+}
+
+// Apply transforms the point (x, y)
+func (m Matrix) Apply(x, y float64) (float64, float64) {
+	return m.A*x + m.C*y + m.E, m.B*x + m.D*y + m.F
+}
+
+// ApplyVector transforms the vector (x, y), ignoring translation
+func (m Matrix) ApplyVector(x, y float64) (float64, float64) {
+	return m.A*x + m.C*y, m.B*x + m.D*y
+}
+
+// Determinant returns the determinant of the linear part
+func (m Matrix) Determinant() float64 {
+	return m.A*m.D - m.B*m.C
+}
+
+// Invert returns the inverse matrix, or ErrSingular
+func (m Matrix) Invert() (Matrix, error) {
+	det := m.Determinant()
+	if math.Abs(det) < epsilon {
+		return Matrix{}, ErrSingular
+	}
+	return Matrix{
+		A: m.D / det,
+		B: -m.B / det,
+		C: -m.C / det,
+		D: m.A / det,
+		E: (m.C*m.F - m.D*m.E) / det,
+		F: (m.B*m.E - m.A*m.F) / det,
+	}, nil
+}
+
+// IsIdentity reports whether m is (within tolerance) the identity
+func (m Matrix) IsIdentity() bool {
+	return m.approx(Identity())
+}
+
+// IsAxisAligned reports whether m has no rotation or skew, so that
+// axis-aligned rectangles stay axis-aligned
+func (m Matrix) IsAxisAligned() bool {
+	return math.Abs(m.B) < epsilon && math.Abs(m.C) < epsilon
+}
+
+// UniformScale returns the scale factor of a similarity transform
+// (translation, rotation and uniform scale only) and whether m is one
+func (m Matrix) UniformScale() (float64, bool) {
+	d := m.Decompose()
+	if math.Abs(d.Skew) > epsilon || math.Abs(d.ScaleX-d.ScaleY) > epsilon {
+		return 0, false
+	}
+	return d.ScaleX, true
+}
+
+func (m Matrix) approx(n Matrix) bool {
+	return math.Abs(m.A-n.A) < epsilon && math.Abs(m.B-n.B) < epsilon &&
+		math.Abs(m.C-n.C) < epsilon && math.Abs(m.D-n.D) < epsilon &&
+		math.Abs(m.E-n.E) < epsilon && math.Abs(m.F-n.F) < epsilon
+}
+
+// String returns the matrix in SVG syntax, e.g. "matrix(1,0,0,1,0,0)"
+func (m Matrix) String() string {
+	return fmt.Sprintf("matrix(%s)", formatNumbers(m.A, m.B, m.C, m.D, m.E, m.F))
+}
+
+// Decomposition expresses a matrix as translate · rotate · skewX · scale.
+// Angles are in degrees.
+type Decomposition struct {
+	X, Y           float64
+	Rotate         float64
+	Skew           float64
+	ScaleX, ScaleY float64
+}
+
+// Decompose splits m into translation, rotation, horizontal skew and
+// non-uniform scale. Reflections show up as a negative ScaleY. The result
+// is only meaningful for invertible matrices.
+func (m Matrix) Decompose() Decomposition {
+	sx := math.Hypot(m.A, m.B)
+	if sx < epsilon {
+		return Decomposition{X: m.E, Y: m.F}
+	}
+	theta := math.Atan2(m.B, m.A)
+	cos, sin := math.Cos(theta), math.Sin(theta)
+	sy := m.Determinant() / sx
+	skew := 0.0
+	if math.Abs(sy) > epsilon {
+		skew = math.Atan((m.C*cos+m.D*sin)/sy) * (180 / math.Pi)
+	}
+	return Decomposition{
+		X:      clean(m.E),
+		Y:      clean(m.F),
+		Rotate: clean(theta * (180 / math.Pi)),
+		Skew:   clean(skew),
+		ScaleX: clean(sx),
+		ScaleY: clean(sy),
+	}
+}
+
+// Matrix recomposes the decomposition
+func (d Decomposition) Matrix() Matrix {
+	return Translate(d.X, d.Y).
+		Multiply(Rotate(d.Rotate)).
+		Multiply(SkewX(d.Skew)).
+		Multiply(Scale(d.ScaleX, d.ScaleY))
+}
+
+// Matrix returns the affine matrix for a legacy T(x,y,scale,rotate) transform
+func (t Transform) Matrix() Matrix {
+	scale := t.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return Translate(t.X, t.Y).Multiply(Rotate(t.Rotate)).Multiply(Scale(scale, scale))
+}
+
+// FromArgs builds a matrix from an EGF transform command and its arguments:
+//
+//	T(x,y,s,r)         translate, uniform scale, rotate (degrees)
+//	T(x,y,sx,sy,r)     translate, non-uniform scale, rotate
+//	T(x,y,sx,sy,r,k)   as above with a horizontal skew of k degrees
+//	TM(a,b,c,d,e,f)    raw affine matrix
+func FromArgs(name string, args []float64) (Matrix, error) {
+	switch name {
+	case "T":
+		switch len(args) {
+		case 4:
+			return Transform{X: args[0], Y: args[1], Scale: args[2], Rotate: args[3]}.Matrix(), nil
+		case 5:
+			return Decomposition{X: args[0], Y: args[1], ScaleX: args[2], ScaleY: args[3], Rotate: args[4]}.Matrix(), nil
+		case 6:
+			return Decomposition{X: args[0], Y: args[1], ScaleX: args[2], ScaleY: args[3], Rotate: args[4], Skew: args[5]}.Matrix(), nil
+		}
+		return Matrix{}, fmt.Errorf("T expects 4, 5 or 6 arguments, got %d", len(args))
+	case "TM":
+		if len(args) != 6 {
+			return Matrix{}, fmt.Errorf("TM expects 6 arguments, got %d", len(args))
+		}
+		return Matrix{A: args[0], B: args[1], C: args[2], D: args[3], E: args[4], F: args[5]}, nil
+	default:
+		return Matrix{}, fmt.Errorf("unknown transform %q", name)
+	}
+}
+
+// Format serializes m in the shortest EGF transform syntax that represents
+// it: T(x,y,s,r) when possible, the 5- or 6-argument T forms for
+// non-uniform scale and skew, and TM(...) for singular matrices
+func Format(m Matrix) string {
+	if math.Abs(m.Determinant()) < epsilon {
+		return "TM(" + formatNumbers(m.A, m.B, m.C, m.D, m.E, m.F) + ")"
+	}
+	d := m.Decompose()
+	switch {
+	case d.Skew != 0:
+		return "T(" + formatNumbers(d.X, d.Y, d.ScaleX, d.ScaleY, d.Rotate, d.Skew) + ")"
+	case d.ScaleX != d.ScaleY:
+		return "T(" + formatNumbers(d.X, d.Y, d.ScaleX, d.ScaleY, d.Rotate) + ")"
+	default:
+		return "T(" + formatNumbers(d.X, d.Y, d.ScaleX, d.Rotate) + ")"
+	}
+}
+
+// clean rounds away floating point noise such as cos(90°) != 0
+func clean(v float64) float64 {
+	r := math.Round(v*1e12) / 1e12
+	if r == 0 {
+		return 0 // normalizes negative zero
+	}
+	return r
+}
+
+func formatNumbers(values ...float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		if v == 0 {
+			v = 0
+		}
+		parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,111 @@
+package transform
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// near reports whether every component of m is within tol of n
+func near(m, n Matrix, tol float64) bool {
+	for _, d := range []float64{m.A - n.A, m.B - n.B, m.C - n.C, m.D - n.D, m.E - n.E, m.F - n.F} {
+		if math.Abs(d) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+// randomMatrices returns n invertible matrices with entries in [-10, 10)
+func randomMatrices(n int) []Matrix {
+	r := rand.New(rand.NewSource(1))
+	entry := func() float64 { return r.Float64()*20 - 10 }
+	var ms []Matrix
+	for len(ms) < n {
+		m := Matrix{A: entry(), B: entry(), C: entry(), D: entry(), E: entry(), F: entry()}
+		if math.Abs(m.Determinant()) > 0.01 {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+func TestDecomposeRecomposes(t *testing.T) {
+	for _, m := range randomMatrices(1000) {
+		d := m.Decompose()
+		if got := d.Matrix(); !near(got, m, 1e-9) {
+			t.Fatalf("%v decomposes to %+v, which recomposes to %v", m, d, got)
+		}
+		if d.ScaleX <= 0 {
+			t.Errorf("%v: ScaleX = %g, want > 0", m, d.ScaleX)
+		}
+		if d.Skew <= -90 || d.Skew >= 90 {
+			t.Errorf("%v: Skew = %g, want within (-90, 90)", m, d.Skew)
+		}
+	}
+}
+
+func TestDecompose(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Matrix
+		want Decomposition
+	}{
+		{"identity", Identity(), Decomposition{ScaleX: 1, ScaleY: 1}},
+		{"translate", Translate(3, -4), Decomposition{X: 3, Y: -4, ScaleX: 1, ScaleY: 1}},
+		{"rotate", Rotate(90), Decomposition{Rotate: 90, ScaleX: 1, ScaleY: 1}},
+		{"scale", Scale(2, 3), Decomposition{ScaleX: 2, ScaleY: 3}},
+		{"reflection", Scale(1, -1), Decomposition{ScaleX: 1, ScaleY: -1}},
+		{"skew", SkewX(45), Decomposition{Skew: 45, ScaleX: 1, ScaleY: 1}},
+		{"composed", Translate(5, 6).Multiply(Rotate(30)).Multiply(Scale(2, 2)), Decomposition{X: 5, Y: 6, Rotate: 30, ScaleX: 2, ScaleY: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Decompose(); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvert(t *testing.T) {
+	for _, m := range randomMatrices(1000) {
+		inv, err := m.Invert()
+		if err != nil {
+			t.Fatalf("%v: %v", m, err)
+		}
+		if got := m.Multiply(inv); !near(got, Identity(), 1e-9) {
+			t.Fatalf("%v times its inverse %v is %v", m, inv, got)
+		}
+		if got := inv.Multiply(m); !near(got, Identity(), 1e-9) {
+			t.Fatalf("inverse %v times %v is %v", inv, m, got)
+		}
+	}
+
+	for _, m := range []Matrix{{}, Scale(0, 1), {A: 1, B: 2, C: 2, D: 4, E: 5}} {
+		if _, err := m.Invert(); !errors.Is(err, ErrSingular) {
+			t.Errorf("%v.Invert() error = %v, want %v", m, err, ErrSingular)
+		}
+	}
+}
+
+func TestUniformScale(t *testing.T) {
+	tests := []struct {
+		m       Matrix
+		k       float64
+		uniform bool
+	}{
+		{Identity(), 1, true},
+		{Translate(3, 4).Multiply(Rotate(30)).Multiply(Scale(2, 2)), 2, true},
+		{Scale(2, 3), 0, false},
+		{SkewX(10), 0, false},
+		{Scale(1, -1), 0, false},
+	}
+	for _, tt := range tests {
+		k, uniform := tt.m.UniformScale()
+		if uniform != tt.uniform || uniform && math.Abs(k-tt.k) > 1e-9 {
+			t.Errorf("%v.UniformScale() = %g, %v, want %g, %v", tt.m, k, uniform, tt.k, tt.uniform)
+		}
+	}
+}
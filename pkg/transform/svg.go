@@ -0,0 +1,145 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSVG parses an SVG transform attribute such as
+// "translate(10,20) rotate(45 5 5) scale(2)" into a single matrix.
+// Supported functions are matrix, translate, scale, rotate, skewX and skewY;
+// they compose left to right as in SVG. An empty list yields the identity.
+func ParseSVG(s string) (Matrix, error) {
+	m := Identity()
+	rest := s
+	for {
+		rest = strings.TrimLeft(rest, " \t\r\n,")
+		if rest == "" {
+			return m, nil
+		}
+
+		lp := strings.IndexByte(rest, '(')
+		if lp == -1 {
+			return Matrix{}, fmt.Errorf("invalid transform %q: missing \"(\"", s)
+		}
+		rp := strings.IndexByte(rest, ')')
+		if rp < lp {
+			return Matrix{}, fmt.Errorf("invalid transform %q: missing \")\"", s)
+		}
+		name := strings.TrimSpace(rest[:lp])
+		args, err := parseNumberList(rest[lp+1 : rp])
+		if err != nil {
+			return Matrix{}, fmt.Errorf("invalid transform %q: %w", s, err)
+		}
+		fn, err := svgFunction(name, args)
+		if err != nil {
+			return Matrix{}, fmt.Errorf("invalid transform %q: %w", s, err)
+		}
+		m = m.Multiply(fn)
+		rest = rest[rp+1:]
+	}
+}
+
+// svgFunction builds the matrix for one SVG transform function
+func svgFunction(name string, args []float64) (Matrix, error) {
+	arity := func(counts ...int) error {
+		for _, n := range counts {
+			if len(args) == n {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s does not take %d arguments", name, len(args))
+	}
+
+	switch name {
+	case "matrix":
+		if err := arity(6); err != nil {
+			return Matrix{}, err
+		}
+		return Matrix{A: args[0], B: args[1], C: args[2], D: args[3], E: args[4], F: args[5]}, nil
+	case "translate":
+		if err := arity(1, 2); err != nil {
+			return Matrix{}, err
+		}
+		if len(args) == 1 {
+			return Translate(args[0], 0), nil
+		}
+		return Translate(args[0], args[1]), nil
+	case "scale":
+		if err := arity(1, 2); err != nil {
+			return Matrix{}, err
+		}
+		if len(args) == 1 {
+			return Scale(args[0], args[0]), nil
+		}
+		return Scale(args[0], args[1]), nil
+	case "rotate":
+		if err := arity(1, 3); err != nil {
+			return Matrix{}, err
+		}
+		if len(args) == 1 {
+			return Rotate(args[0]), nil
+		}
+		cx, cy := args[1], args[2]
+		return Translate(cx, cy).Multiply(Rotate(args[0])).Multiply(Translate(-cx, -cy)), nil
+	case "skewX":
+		if err := arity(1); err != nil {
+			return Matrix{}, err
+		}
+		return SkewX(args[0]), nil
+	case "skewY":
+		if err := arity(1); err != nil {
+			return Matrix{}, err
+		}
+		return SkewY(args[0]), nil
+	default:
+		return Matrix{}, fmt.Errorf("unknown function %q", name)
+	}
+}
+
+// parseNumberList parses numbers separated by whitespace and/or commas.
+// Like SVG, a sign or a second decimal point also starts a new number,
+// so "10-5" and "0.5.5" are two numbers each.
+func parseNumberList(s string) ([]float64, error) {
+	var nums []float64
+	i := 0
+	for {
+		for i < len(s) && strings.IndexByte(" \t\r\n,", s[i]) != -1 {
+			i++
+		}
+		if i >= len(s) {
+			return nums, nil
+		}
+
+		start := i
+		if s[i] == '+' || s[i] == '-' {
+			i++
+		}
+		seenDot := false
+		for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' && !seenDot) {
+			if s[i] == '.' {
+				seenDot = true
+			}
+			i++
+		}
+		if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+			i++
+			if i < len(s) && (s[i] == '+' || s[i] == '-') {
+				i++
+			}
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+		}
+
+		v, err := strconv.ParseFloat(s[start:i], 64)
+		if err != nil {
+			if i == start {
+				i++
+			}
+			return nil, fmt.Errorf("invalid number %q", s[start:i])
+		}
+		nums = append(nums, v)
+	}
+}
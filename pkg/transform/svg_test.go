@@ -0,0 +1,64 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSVG(t *testing.T) {
+	tests := []struct {
+		name, s string
+		want    Matrix
+	}{
+		{"empty list", "", Identity()},
+		{"only whitespace", " \t\n", Identity()},
+		{"translate", "translate(10,20)", Translate(10, 20)},
+		{"translate with one argument", "translate(10)", Translate(10, 0)},
+		{"whitespace separators", "translate( 10 20 )", Translate(10, 20)},
+		{"comma and whitespace separators", "translate(10 , 20)", Translate(10, 20)},
+		{"signs separate numbers", "translate(10-20)", Translate(10, -20)},
+		{"second decimal point separates numbers", "translate(.5.5)", Translate(0.5, 0.5)},
+		{"exponents", "translate(1e1,2E-1)", Translate(10, 0.2)},
+		{"uniform scale", "scale(2)", Scale(2, 2)},
+		{"scale", "scale(2,3)", Scale(2, 3)},
+		{"rotate", "rotate(90)", Matrix{C: -1, B: 1}},
+		{"rotate about a centre point", "rotate(90 10 10)", Matrix{B: 1, C: -1, E: 20}},
+		{"skewX", "skewX(45)", Matrix{A: 1, C: 1, D: 1}},
+		{"skewY", "skewY(45)", Matrix{A: 1, B: 1, D: 1}},
+		{"matrix", "matrix(1,2,3,4,5,6)", Matrix{A: 1, B: 2, C: 3, D: 4, E: 5, F: 6}},
+		{"list composes left to right", "translate(10,0) scale(2)", Matrix{A: 2, D: 2, E: 10}},
+		{"comma between functions", "scale(2),translate(10,0)", Matrix{A: 2, D: 2, E: 20}},
+		{"no separator between functions", "scale(2)translate(10,0)", Matrix{A: 2, D: 2, E: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseSVG(tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !near(m, tt.want, 1e-12) {
+				t.Errorf("ParseSVG(%q) = %v, want %v", tt.s, m, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSVGErrors(t *testing.T) {
+	tests := []struct {
+		s, err string
+	}{
+		{"translate", `missing "("`},
+		{"translate(10", `missing ")"`},
+		{"spin(10)", `unknown function "spin"`},
+		{"rotate(10,5)", "rotate does not take 2 arguments"},
+		{"matrix(1,2,3)", "matrix does not take 3 arguments"},
+		{"skewX()", "skewX does not take 0 arguments"},
+		{"scale(a)", `invalid number "a"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseSVG(tt.s)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseSVG(%q) error = %v, want %q", tt.s, err, tt.err)
+		}
+	}
+}
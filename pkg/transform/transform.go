@@ -43,19 +43,29 @@ func (t Transform) ApplyToPoint(x, y float64) (float64, float64) {
 	return xFinal, yFinal
 }
 
-// ParseTransform parses a transform string like T(x,y,scale,rotate)
-func ParseTransform(s string) Transform {
-	r := regexp.MustCompile(`T\(([^,]+),([^,]+),([^,]+),([^)]+)\)`)
-	m := r.FindStringSubmatch(s)
-	if len(m) != 5 {
-		return NewTransform() // Default: no translation, scale=1, no rotation
+// transformPattern matches T(...) and TM(...) transform commands
+var transformPattern = regexp.MustCompile(`\b(TM|T)\(([^)]*)\)`)
+
+// ParseTransform parses an EGF transform: the classic T(x,y,scale,rotate),
+// the extended T(...) forms or a raw TM(a,b,c,d,e,f) matrix (see FromArgs).
+// Malformed input yields the identity.
+func ParseTransform(s string) Matrix {
+	m := transformPattern.FindStringSubmatch(s)
+	if len(m) != 3 {
+		return Identity() // Default: no translation, scale=1, no rotation
 	}
-	x, _ := strconv.ParseFloat(strings.TrimSpace(m[1]), 64)
-	y, _ := strconv.ParseFloat(strings.TrimSpace(m[2]), 64)
-	scale, _ := strconv.ParseFloat(strings.TrimSpace(m[3]), 64)
-	if scale == 0 {
-		scale = 1 // Default scale
+	parts := strings.Split(m[2], ",")
+	args := make([]float64, len(parts))
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return Identity()
+		}
+		args[i] = v
+	}
+	matrix, err := FromArgs(m[1], args)
+	if err != nil {
+		return Identity()
 	}
-	rot, _ := strconv.ParseFloat(strings.TrimSpace(m[4]), 64)
-	return Transform{x, y, scale, rot}
+	return matrix
 }
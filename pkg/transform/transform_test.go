@@ -0,0 +1,74 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []float64
+		want Matrix
+		err  string
+	}{
+		{"T", []float64{10, 20, 2, 90}, Matrix{A: 0, B: 2, C: -2, D: 0, E: 10, F: 20}, ""},
+		{"T", []float64{1, 2, 0, 0}, Translate(1, 2), ""}, // a zero scale is read as 1
+		{"T", []float64{1, 2, 2, 3, 0}, Matrix{A: 2, D: 3, E: 1, F: 2}, ""},
+		{"T", []float64{0, 0, 2, 3, 90}, Matrix{B: 2, C: -3}, ""},
+		{"T", []float64{0, 0, 1, 1, 0, 45}, SkewX(45), ""},
+		{"T", []float64{5, 5, 2, 2, 0, 45}, Matrix{A: 2, C: 2, D: 2, E: 5, F: 5}, ""},
+		{"TM", []float64{1, 2, 3, 4, 5, 6}, Matrix{A: 1, B: 2, C: 3, D: 4, E: 5, F: 6}, ""},
+		{"T", []float64{1, 2, 3}, Matrix{}, "T expects 4, 5 or 6 arguments, got 3"},
+		{"T", []float64{1, 2, 3, 4, 5, 6, 7}, Matrix{}, "T expects 4, 5 or 6 arguments, got 7"},
+		{"TM", []float64{1, 2, 3, 4, 5}, Matrix{}, "TM expects 6 arguments, got 5"},
+		{"X", []float64{1}, Matrix{}, `unknown transform "X"`},
+	}
+	for _, tt := range tests {
+		m, err := FromArgs(tt.name, tt.args)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("FromArgs(%s, %v) error = %v, want %q", tt.name, tt.args, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FromArgs(%s, %v): %v", tt.name, tt.args, err)
+		} else if !near(m, tt.want, 1e-12) {
+			t.Errorf("FromArgs(%s, %v) = %v, want %v", tt.name, tt.args, m, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		m    Matrix
+		want string
+	}{
+		{Identity(), "T(0,0,1,0)"},
+		{Translate(5, -2.5), "T(5,-2.5,1,0)"},
+		{Translate(10, 20).Multiply(Rotate(45)).Multiply(Scale(2, 2)), "T(10,20,2,45)"},
+		{Scale(2, 3), "T(0,0,2,3,0)"},
+		{Scale(1, -1), "T(0,0,1,-1,0)"},
+		{Rotate(30).Multiply(SkewX(15)).Multiply(Scale(2, 1)), "T(0,0,2,1,30,15)"},
+		{Matrix{A: 1, B: 2, C: 2, D: 4, E: 5, F: 6}, "TM(1,2,2,4,5,6)"},
+		{Matrix{}, "TM(0,0,0,0,0,0)"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.m); got != tt.want {
+			t.Errorf("Format(%v) = %s, want %s", tt.m, got, tt.want)
+		}
+	}
+}
+
+// TestFormatParses checks that ParseTransform reads Format's output back
+// as the same matrix
+func TestFormatParses(t *testing.T) {
+	ms := append(randomMatrices(200), Matrix{A: 1, B: 2, C: 2, D: 4, E: 5, F: 6})
+	for _, m := range ms {
+		s := Format(m)
+		if got := ParseTransform(s); !near(got, m, 1e-9) {
+			t.Errorf("%v formats as %s, which parses as %v", m, s, got)
+		}
+	}
+}